@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"snippetbox/internal/mailer"
+	"snippetbox/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long the link in a verification email stays valid.
+const verificationTokenTTL = 24 * time.Hour
+
+// How long the link in a password reset email stays valid.
+const passwordResetTokenTTL = time.Hour
+
+// The purpose prefixed to the value of verification tokens, so that tokens signed for other
+// purposes can't be used to verify an email address.
+const verifyEmailPurpose = "verify-email"
+
+// sendVerificationEmail sends user a link which verifies their email address when followed.
+func (app *application) sendVerificationEmail(user models.User) error {
+    value := verifyEmailPurpose + ":" + strconv.Itoa(user.ID) + ":" + user.Email
+    token := app.tokens.Sign(value, time.Now().Add(verificationTokenTTL))
+    link := app.baseURL + "/user/verify?token=" + url.QueryEscape(token)
+
+    to := mail.Address{Name: user.Name, Address: user.Email}
+
+    return app.mailer.Send(mailer.Message{
+        To:      to.String(),
+        Subject: "Verify your email address",
+        Body: fmt.Sprintf("Hi %s,\n\n"+
+            "Thanks for signing up to Snippetbox. Please follow the link below to verify your email\n"+
+            "address. The link is valid for 24 hours.\n\n"+
+            "%s\n\n"+
+            "If you didn't sign up, you can ignore this email.\n", user.Name, link),
+    })
+}
+
+// parseVerificationToken returns the user ID and email address carried by a token sent by
+// sendVerificationEmail, or errInvalidToken if the token isn't valid.
+func (app *application) parseVerificationToken(token string) (int, string, error) {
+    value, err := app.tokens.Verify(token, time.Now())
+    if err != nil {
+        return 0, "", err
+    }
+
+    purpose, rest, _ := strings.Cut(value, ":")
+    idString, email, ok := strings.Cut(rest, ":")
+    if purpose != verifyEmailPurpose || !ok {
+        return 0, "", errInvalidToken
+    }
+
+    id, err := strconv.Atoi(idString)
+    if err != nil {
+        return 0, "", errInvalidToken
+    }
+
+    return id, email, nil
+}
+
+// sendPasswordResetEmail sends the user with the email address email a link to a page on which
+// they can choose a new password. Nothing is sent if there is no such user.
+func (app *application) sendPasswordResetEmail(email string) error {
+    user, err := app.user.GetByEmail(email)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            return nil
+        }
+
+        return err
+    }
+
+    token, err := app.passwordReset.Insert(user.ID, passwordResetTokenTTL)
+    if err != nil {
+        return err
+    }
+
+    link := app.baseURL + "/user/password/reset/" + token
+
+    to := mail.Address{Name: user.Name, Address: user.Email}
+
+    return app.mailer.Send(mailer.Message{
+        To:      to.String(),
+        Subject: "Reset your password",
+        Body: fmt.Sprintf("Hi %s,\n\n"+
+            "Someone asked to reset the password of your Snippetbox account. Please follow the link\n"+
+            "below to choose a new password. The link is valid for one hour and can be used once.\n\n"+
+            "%s\n\n"+
+            "If you didn't ask to reset your password, you can ignore this email. Your password\n"+
+            "won't be changed.\n", user.Name, link),
+    })
+}
+
+// sendLockoutEmail tells the user with the email address email that logins to their account have
+// been locked out after failures failed attempts, the last from the IP address ip. Nothing is sent
+// if there is no such user.
+func (app *application) sendLockoutEmail(email, ip string, failures int) error {
+    user, err := app.user.GetByEmail(email)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            return nil
+        }
+
+        return err
+    }
+
+    to := mail.Address{Name: user.Name, Address: user.Email}
+
+    return app.mailer.Send(mailer.Message{
+        To:      to.String(),
+        Subject: "Logins to your account have been locked",
+        Body: fmt.Sprintf("Hi %s,\n\n"+
+            "There have been %d failed attempts to log in to your Snippetbox account, the last one\n"+
+            "from the IP address %s, so we've locked logins to it for %s.\n\n"+
+            "If this was you, you can try again later or reset your password here:\n\n"+
+            "%s/user/password/forgot\n\n"+
+            "If it wasn't you, someone may be trying to guess your password. Make sure that it is\n"+
+            "strong and not used anywhere else, and consider turning on two-factor authentication.\n",
+            user.Name, failures, ip, retryText(lockoutDuration), app.baseURL),
+    })
+}
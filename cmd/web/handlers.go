@@ -1,12 +1,28 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
+	"snippetbox/internal/diff"
+	"snippetbox/internal/highlight"
 	"snippetbox/internal/models"
+	"snippetbox/internal/qrcode"
+	"snippetbox/internal/totp"
 	"snippetbox/internal/validator"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func ping(w http.ResponseWriter, r *http.Request) {
@@ -17,10 +33,25 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
     snippets, err := app.snippet.Latest(10)
     if err != nil {
         app.serverError(w, r, err)
+        return
+    }
+
+    tags, err := app.snippet.PopularTags(30)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    mostStarred, err := app.snippet.MostStarred(time.Now().AddDate(0, 0, -7), 5)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
     }
 
     data := app.newTemplateData(r)
     data.Snippets = snippets
+    data.TagCloud = newTagCloud(tags)
+    data.MostStarred = mostStarred
 
     app.render(w, r, http.StatusOK, "home.html", data)
 }
@@ -30,6 +61,140 @@ func (app *application) about(w http.ResponseWriter, r *http.Request) {
     app.render(w, r, http.StatusOK, "about.html", data)
 }
 
+// archivePageSize is the number of snippets shown on a page of the archive.
+const archivePageSize = 20
+
+// archive lists all public snippets from newest to oldest.
+func (app *application) archive(w http.ResponseWriter, r *http.Request) {
+    app.renderArchive(w, r, "")
+}
+
+// tagView lists the public snippets with the tag in the path from newest to oldest.
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+    tag := r.PathValue("name")
+    if !validator.Match(tag, validator.TagRX) {
+        http.NotFound(w, r)
+        return
+    }
+
+    app.renderArchive(w, r, tag)
+}
+
+// renderArchive renders a page of the public snippets, or of those with the given tag if it isn't
+// empty. Pages are addressed by a cursor rather than a page number: ?after=c shows the snippets
+// older than the cursor c and ?before=c the ones newer than it, so pages don't shift when snippets
+// are created while someone browses.
+func (app *application) renderArchive(w http.ResponseWriter, r *http.Request, tag string) {
+    var (
+        cursor models.Cursor
+        newer  bool
+        err    error
+    )
+
+    after, before := r.URL.Query().Get("after"), r.URL.Query().Get("before")
+
+    switch {
+    case after != "" && before != "":
+        app.clientError(w, http.StatusBadRequest)
+        return
+    case after != "":
+        cursor, err = models.ParseCursor(after)
+    case before != "":
+        cursor, err = models.ParseCursor(before)
+        newer = true
+    }
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    // Ask for one more snippet than fits on a page to find out if there is a further page in the
+    // direction of travel.
+    snippets, err := app.snippet.Archive(tag, cursor, archivePageSize+1, newer)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    more := len(snippets) > archivePageSize
+    if more {
+        if newer {
+            snippets = snippets[1:]
+        } else {
+            snippets = snippets[:archivePageSize]
+        }
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippets = snippets
+    data.Tag = tag
+
+    if len(snippets) > 0 {
+        first, last := models.CursorAt(snippets[0]), models.CursorAt(snippets[len(snippets)-1])
+
+        base := "/snippets"
+        if tag != "" {
+            base = "/tag/" + tag
+        }
+
+        // Coming from an older page means there is one, and likewise for a newer page.
+        if more && newer || !cursor.IsZero() && !newer {
+            data.Pagination.Prev = base + "?before=" + first.String()
+        }
+        if more && !newer || newer {
+            data.Pagination.Next = base + "?after=" + last.String()
+        }
+    }
+
+    app.render(w, r, http.StatusOK, "archive.html", data)
+}
+
+// searchPageSize is the number of search results shown on a page.
+const searchPageSize = 10
+
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+    query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+    page := 1
+    if p := r.URL.Query().Get("page"); p != "" {
+        var err error
+
+        page, err = strconv.Atoi(p)
+        if err != nil || page < 1 {
+            app.clientError(w, http.StatusBadRequest)
+            return
+        }
+    }
+
+    data := app.newTemplateData(r)
+    data.Query = query
+
+    if query != "" {
+        // Ask for one more snippet than fits on a page to find out if there is a next page.
+        snippets, err := app.snippet.Search(query, app.authenticatedUserID(r), searchPageSize+1, (page-1)*searchPageSize)
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        pageURL := func(page int) string {
+            return "/search?" + url.Values{"q": {query}, "page": {strconv.Itoa(page)}}.Encode()
+        }
+
+        if len(snippets) > searchPageSize {
+            snippets = snippets[:searchPageSize]
+            data.Pagination.Next = pageURL(page + 1)
+        }
+        if page > 1 {
+            data.Pagination.Prev = pageURL(page - 1)
+        }
+
+        data.Snippets = snippets
+    }
+
+    app.render(w, r, http.StatusOK, "search.html", data)
+}
+
 type userSignupForm struct {
     Name                string `form:"name"`
     Email               string `form:"email"`
@@ -66,7 +231,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    err = app.user.Insert(form.Name, form.Email, form.Password)
+    id, err := app.user.Insert(form.Name, form.Email, form.Password)
     if err != nil {
         if errors.Is(err, models.ErrDuplicateEmail) {
             form.AddFieldError("email", "Email address is already in use.")
@@ -81,7 +246,35 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please login.")
+    // The account exists whether or not the email can be sent, so just log the failure. The user
+    // gets a new link when they try to log in.
+    err = app.sendVerificationEmail(models.User{ID: id, Name: form.Name, Email: form.Email})
+    if err != nil {
+        app.logger.Error(err.Error(), "user", id)
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please follow the link we've emailed you to verify your email address, then login.")
+
+    http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *application) userVerify(w http.ResponseWriter, r *http.Request) {
+    id, email, err := app.parseVerificationToken(r.URL.Query().Get("token"))
+    if err == nil {
+        err = app.user.Verify(id, email)
+    }
+    if err != nil {
+        if errors.Is(err, errInvalidToken) || errors.Is(err, models.ErrNoRecord) {
+            app.sessionManager.Put(r.Context(), "flash", "That verification link is invalid or has expired. Login to get a new one.")
+            http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+        } else {
+            app.serverError(w, r, err)
+        }
+
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Your email address has been verified. Please login.")
 
     http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 }
@@ -118,14 +311,62 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    ipSubject, accountSubject := loginSubjects(r, form.Email)
+
+    // Refuse to check the password at all while the client or the account is blocked, so that
+    // guesses can't be made faster than the backoff allows.
+    until, err := app.loginFailure.BlockedUntil(ipSubject, accountSubject)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    if wait := time.Until(until); wait > 0 {
+        w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+        form.AddNonFieldError(fmt.Sprintf("Too many failed login attempts. Please try again in %s.", retryText(wait)))
+
+        data := app.newTemplateData(r)
+        data.Form = form
+
+        app.render(w, r, http.StatusTooManyRequests, "login.html", data)
+        return
+    }
+
     id, err := app.user.Authenticate(form.Email, form.Password)
     if err != nil {
         if errors.Is(err, models.ErrInvalidCredentials) {
+            err = app.recordLoginFailure(r, form.Email)
+            if err != nil {
+                app.serverError(w, r, err)
+                return
+            }
+
             form.AddNonFieldError("Email or password is incorrect.")
 
             data := app.newTemplateData(r)
             data.Form = form
 
+            app.render(w, r, http.StatusUnprocessableEntity, "login.html", data)
+        } else if errors.Is(err, models.ErrUnverifiedEmail) {
+            // The password was right, so it's safe to tell the user about their account.
+            user, err := app.user.Get(id)
+            if err != nil {
+                app.serverError(w, r, err)
+                return
+            }
+
+            err = app.sendVerificationEmail(user)
+            if err != nil {
+                app.serverError(w, r, err)
+                return
+            }
+
+            form.AddNonFieldError("You need to verify your email address before you can login. We've emailed you a new verification link.")
+
+            data := app.newTemplateData(r)
+            data.Form = form
+
             app.render(w, r, http.StatusUnprocessableEntity, "login.html", data)
         } else {
             app.serverError(w, r, err)
@@ -134,10 +375,45 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // The password was right, so the account's failures no longer count. The client's still do,
+    // so that an attacker can't reset them by logging in to their own account.
+    err = app.loginFailure.Reset(accountSubject)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    user, err := app.user.Get(id)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    if user.TOTPEnabled {
+        // The password was right, but the user isn't logged in until they have entered a
+        // one-time password too. Until then the session only records who they claim to be.
+        err = app.sessionManager.RenewToken(r.Context())
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        app.sessionManager.Put(r.Context(), "pendingTwoFactorUserID", id)
+        app.sessionManager.Put(r.Context(), "pendingTwoFactorExpires", time.Now().Add(pendingTwoFactorTTL).Unix())
+
+        http.Redirect(w, r, "/user/login/2fa", http.StatusSeeOther)
+        return
+    }
+
+    app.logIn(w, r, id)
+}
+
+// logIn logs the user id in and redirects them to the page they were trying to reach.
+func (app *application) logIn(w http.ResponseWriter, r *http.Request, id int) {
     // Use the RenewToken() method on the current session to change the session ID. It's a good
     // practice to generate a new session ID when the authentication state or privilage level
     // changes for the user (e.g. login and logout operations).
-    err = app.sessionManager.RenewToken(r.Context())
+    err := app.sessionManager.RenewToken(r.Context())
     if err != nil {
         app.serverError(w, r, err)
         return
@@ -146,6 +422,13 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
     // Add the ID of the current user to the session, so that they are now 'logged in'.
     app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
 
+    // Index the new session by user, so that it is listed on their account page.
+    err = app.session.Insert(app.sessionManager.Token(r.Context()), id, r.UserAgent(), clientIP(r))
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
     // Use the PopString method to retrieve and remove a value from the session data in one step.
     // If no matching key exists this will return the empty string.
     path := app.sessionManager.PopString(r.Context(), "redirectPathAfterLogin")
@@ -157,57 +440,113 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
     http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
 }
 
-func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
-    // Use the RenewToken() method on the current session to change the session ID again.
-    err := app.sessionManager.RenewToken(r.Context())
-    if err != nil {
-        app.serverError(w, r, err)
-        return
+// How long a user who has entered their password has to enter a one-time password.
+const pendingTwoFactorTTL = 5 * time.Minute
+
+// pendingTwoFactorUserID returns the ID of the user who has entered their password and still has
+// to enter a one-time password, or 0 if there is no such user or they took too long.
+func (app *application) pendingTwoFactorUserID(r *http.Request) int {
+    expires := app.sessionManager.GetInt64(r.Context(), "pendingTwoFactorExpires")
+    if time.Now().Unix() >= expires {
+        return 0
     }
 
-    // Remove the authenticatedUserID from the session data so that the user is 'logged out'.
-    app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+    return app.sessionManager.GetInt(r.Context(), "pendingTwoFactorUserID")
+}
 
-    app.sessionManager.Put(r.Context(), "flash", "You've logged out successfully.")
+type userLoginTwoFactorForm struct {
+    Code                string `form:"code"`
+    validator.Validator `form:"-"`
+}
 
-    http.Redirect(w, r, "/", http.StatusSeeOther)
+func (app *application) userLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+    if app.pendingTwoFactorUserID(r) == 0 {
+        http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Form = userLoginTwoFactorForm{}
+
+    app.render(w, r, http.StatusOK, "login_2fa.html", data)
 }
 
-func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
-    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+func (app *application) userLoginTwoFactorPost(w http.ResponseWriter, r *http.Request) {
+    id := app.pendingTwoFactorUserID(r)
+    if id == 0 {
+        app.sessionManager.Put(r.Context(), "flash", "Your login has timed out. Please login again.")
+        http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+        return
+    }
 
-    user, err := app.user.Get(userID)
+    var form userLoginTwoFactorForm
+
+    err := app.decodePostForm(r, &form)
     if err != nil {
-        if errors.Is(err, models.ErrNoRecord) {
-            http.Redirect(w, r, "/user/signup", http.StatusSeeOther)
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.Code = strings.TrimSpace(form.Code)
+
+    form.CheckField(validator.NotEmpty(form.Code), "code", "This field cannot be empty.")
+
+    // The one-time passwords are short, so only a few guesses are allowed.
+    if form.Valid() && !app.twoFactorLimiter.Allow(id) {
+        form.AddNonFieldError("Too many incorrect codes. Please try again later.")
+
+        data := app.newTemplateData(r)
+        data.Form = form
+
+        app.render(w, r, http.StatusTooManyRequests, "login_2fa.html", data)
+        return
+    }
+
+    if form.Valid() {
+        // Anything which isn't a one-time password is tried as a recovery code.
+        if len(form.Code) == totp.Digits && strings.Trim(form.Code, "0123456789") == "" {
+            err = app.user.CheckTOTP(id, form.Code, time.Now())
         } else {
+            err = app.user.UseRecoveryCode(id, form.Code)
+        }
+
+        if errors.Is(err, models.ErrInvalidCredentials) {
+            app.twoFactorLimiter.Fail(id)
+            form.AddFieldError("code", "The code is incorrect.")
+        } else if err != nil {
             app.serverError(w, r, err)
+            return
         }
+    }
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Form = form
+
+        app.render(w, r, http.StatusUnprocessableEntity, "login_2fa.html", data)
         return
     }
 
-    data := app.newTemplateData(r)
-    data.User = user
+    app.sessionManager.Remove(r.Context(), "pendingTwoFactorUserID")
+    app.sessionManager.Remove(r.Context(), "pendingTwoFactorExpires")
 
-    app.render(w, r, http.StatusOK, "account.html", data)
+    app.logIn(w, r, id)
 }
 
-type accountPasswordUpdateForm struct {
-    CurrentPassword         string `form:"currentPassword"`
-    NewPassword             string `form:"newPassword"`
-    NewPasswordConfirmation string `form:"newPasswordConfirmation"`
-    validator.Validator     `form:"-"`
+type userPasswordForgotForm struct {
+    Email               string `form:"email"`
+    validator.Validator `form:"-"`
 }
 
-func (app *application) accountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
+func (app *application) userPasswordForgot(w http.ResponseWriter, r *http.Request) {
     data := app.newTemplateData(r)
-    data.Form = accountPasswordUpdateForm{}
+    data.Form = userPasswordForgotForm{}
 
-    app.render(w, r, http.StatusOK, "password.html", data)
+    app.render(w, r, http.StatusOK, "password_forgot.html", data)
 }
 
-func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http.Request) {
-    var form accountPasswordUpdateForm
+func (app *application) userPasswordForgotPost(w http.ResponseWriter, r *http.Request) {
+    var form userPasswordForgotForm
 
     err := app.decodePostForm(r, &form)
     if err != nil {
@@ -215,83 +554,68 @@ func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http
         return
     }
 
-    form.CheckField(validator.NotEmpty(form.CurrentPassword), "currentPassword", "This field cannot be empty.")
-    form.CheckField(validator.NotEmpty(form.NewPassword), "newPassword", "This field cannot be empty.")
-    form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "This field must be at least 8 characters long.")
-    form.CheckField(validator.NotEmpty(form.NewPasswordConfirmation), "newPasswordConfirmation", "This field cannot be empty.")
-    form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "Passwords do not match.")
+    form.CheckField(validator.NotEmpty(form.Email), "email", "This field cannot be empty.")
+    form.CheckField(validator.Match(form.Email, validator.EmailRX), "email", "This field must be a valid email address.")
 
     if !form.Valid() {
         data := app.newTemplateData(r)
         data.Form = form
 
-        app.render(w, r, http.StatusUnprocessableEntity, "password.html", data)
+        app.render(w, r, http.StatusUnprocessableEntity, "password_forgot.html", data)
         return
     }
 
-    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+    // Look up the user and send the email in the background, so that neither the response nor
+    // the time it takes reveals whether the email address belongs to an account.
+    app.background(func() {
+        err := app.sendPasswordResetEmail(form.Email)
+        if err != nil {
+            app.logger.Error(err.Error())
+        }
+    })
 
-    err = app.user.UpdatePassword(userID, form.CurrentPassword, form.NewPassword)
-    if err != nil {
-        if errors.Is(err, models.ErrInvalidCredentials) {
-            form.AddFieldError("currentPassword", "Current password is incorrect.")
+    app.sessionManager.Put(r.Context(), "flash", "If an account uses that email address, we've emailed it a link to reset the password.")
 
-            data := app.newTemplateData(r)
-            data.Form = form
+    http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
 
-            app.render(w, r, http.StatusUnprocessableEntity, "password.html", data)
-        } else {
-            app.serverError(w, r, err)
-        }
-        return
-    }
+type userPasswordResetForm struct {
+    NewPassword             string `form:"newPassword"`
+    NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+    Token                   string `form:"-"`
+    validator.Validator     `form:"-"`
+}
 
-    app.sessionManager.Put(r.Context(), "flash", "Your password has been updated.")
+// invalidPasswordResetToken sends the user back to the page on which they can ask for a new
+// password reset link.
+func (app *application) invalidPasswordResetToken(w http.ResponseWriter, r *http.Request) {
+    app.sessionManager.Put(r.Context(), "flash", "That password reset link is invalid or has expired. Please ask for a new one.")
 
-    http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+    http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
 }
 
-func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-    id, err := strconv.Atoi(r.PathValue("id"))
-    if err != nil || id < 1 {
-        http.NotFound(w, r)
-        return
-    }
+func (app *application) userPasswordReset(w http.ResponseWriter, r *http.Request) {
+    token := r.PathValue("token")
 
-    snippet, err := app.snippet.Get(id)
+    _, err := app.passwordReset.Check(token)
     if err != nil {
         if errors.Is(err, models.ErrNoRecord) {
-            http.NotFound(w, r)
+            app.invalidPasswordResetToken(w, r)
         } else {
             app.serverError(w, r, err)
         }
+
         return
     }
 
     data := app.newTemplateData(r)
-    data.Snippet = snippet
-
-    app.render(w, r, http.StatusOK, "snippet_view.html", data)
-}
-
-type snippetCreateForm struct {
-    Title               string `form:"title"`
-    Content             string `form:"content"`
-    Expires             int    `form:"expires"`
-    validator.Validator `form:"-"`
-}
-
-func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
-    data := app.newTemplateData(r)
-    data.Form = snippetCreateForm{
-        Expires: 365,
-    }
+    data.Form = userPasswordResetForm{Token: token}
 
-    app.render(w, r, http.StatusOK, "snippet_create.html", data)
+    app.render(w, r, http.StatusOK, "password_reset.html", data)
 }
 
-func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
-    form := snippetCreateForm{}
+func (app *application) userPasswordResetPost(w http.ResponseWriter, r *http.Request) {
+    form := userPasswordResetForm{Token: r.PathValue("token")}
 
     err := app.decodePostForm(r, &form)
     if err != nil {
@@ -299,25 +623,1483 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
         return
     }
 
-    form.CheckField(validator.NotEmpty(form.Title), "title", "This field cannot be empty.")
-    form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long.")
-    form.CheckField(validator.NotEmpty(form.Content), "content", "This field cannot be empty.")
-    form.CheckField(validator.PermittedValue(form.Expires, 365, 7, 1), "expires", "This field must equal 1, 7, or 365.")
-
+    form.CheckField(validator.NotEmpty(form.NewPassword), "newPassword", "This field cannot be empty.")
+    form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "This field must be at least 8 characters long.")
+    form.CheckField(validator.NotEmpty(form.NewPasswordConfirmation), "newPasswordConfirmation", "This field cannot be empty.")
+    form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "Passwords do not match.")
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Form = form
+
+        app.render(w, r, http.StatusUnprocessableEntity, "password_reset.html", data)
+        return
+    }
+
+    userID, err := app.passwordReset.Reset(form.Token, form.NewPassword)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.invalidPasswordResetToken(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+
+        return
+    }
+
+    // Whoever knew the old password may still be logged in, so log the user out everywhere.
+    err = app.destroyUserSessions(r.Context(), userID, "")
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    err = app.sessionManager.RenewToken(r.Context())
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+
+    app.sessionManager.Put(r.Context(), "flash", "Your password has been reset. Please login.")
+
+    http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
+    // Use the RenewToken() method on the current session to change the session ID again.
+    err := app.sessionManager.RenewToken(r.Context())
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    // Remove the authenticatedUserID from the session data so that the user is 'logged out'.
+    app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+
+    app.sessionManager.Put(r.Context(), "flash", "You've logged out successfully.")
+
+    http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
+    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+    user, err := app.user.Get(userID)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.Redirect(w, r, "/user/signup", http.StatusSeeOther)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    snippets, err := app.snippet.ByUser(userID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    starred, err := app.snippet.Starred(userID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    sessions, err := app.session.ByUser(userID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.User = user
+    data.Snippets = snippets
+    data.Starred = starred
+    data.Sessions = sessions
+
+    token := app.sessionManager.Token(r.Context())
+    for _, s := range sessions {
+        if s.Token == token {
+            data.CurrentSessionID = s.ID
+        }
+    }
+
+    app.render(w, r, http.StatusOK, "account.html", data)
+}
+
+// accountSessionRevokePost logs out one of the sessions of the current user. If it is the session
+// which made the request, the user is logged out like by userLogoutPost.
+func (app *application) accountSessionRevokePost(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil || id < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+    token, err := app.session.Revoke(id, userID)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    if token == app.sessionManager.Token(r.Context()) {
+        // RenewToken deletes the current session from the store.
+        err = app.sessionManager.RenewToken(r.Context())
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+
+        app.sessionManager.Put(r.Context(), "flash", "You've logged out successfully.")
+
+        http.Redirect(w, r, "/", http.StatusSeeOther)
+        return
+    }
+
+    err = app.sessionManager.Store.Delete(token)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "The session has been signed out.")
+
+    http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+// accountSessionRevokeOthersPost logs out all the sessions of the current user except the one which
+// made the request.
+func (app *application) accountSessionRevokeOthersPost(w http.ResponseWriter, r *http.Request) {
+    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+    err := app.destroyUserSessions(r.Context(), userID, app.sessionManager.Token(r.Context()))
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "You've been signed out everywhere else.")
+
+    http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+type accountTwoFactorForm struct {
+    Code                string `form:"code"`
+    Password            string `form:"password"`
+    validator.Validator `form:"-"`
+}
+
+// renderAccountTwoFactor renders the two-factor authentication page of user. If user hasn't turned
+// it on, the page shows a QR code with which they can add their account to an authenticator app.
+// The secret it holds is kept in the session until the user confirms it with a one-time password.
+func (app *application) renderAccountTwoFactor(w http.ResponseWriter, r *http.Request, status int, user models.User, form accountTwoFactorForm) {
+    data := app.newTemplateData(r)
+    data.User = user
+    data.Form = form
+
+    if !user.TOTPEnabled {
+        secret := app.sessionManager.GetString(r.Context(), "totpEnrollmentSecret")
+        if secret == "" {
+            var err error
+
+            secret, err = totp.NewSecret()
+            if err != nil {
+                app.serverError(w, r, err)
+                return
+            }
+
+            app.sessionManager.Put(r.Context(), "totpEnrollmentSecret", secret)
+        }
+
+        code, err := qrcode.Encode([]byte(totp.URI("Snippetbox", user.Email, secret)), qrcode.M)
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        data.TOTPSecret = secret
+        data.QRCode = template.HTML(code.SVG(240))
+
+        // The page holds the secret, so it mustn't be kept by browsers or proxies.
+        w.Header().Set("Cache-Control", "no-store")
+    }
+
+    app.render(w, r, status, "account_2fa.html", data)
+}
+
+// currentUser returns the user making the request. It sends a server error if the user can't be
+// loaded, in which case ok is false.
+func (app *application) currentUser(w http.ResponseWriter, r *http.Request) (user models.User, ok bool) {
+    user, err := app.user.Get(app.authenticatedUserID(r))
+    if err != nil {
+        app.serverError(w, r, err)
+        return models.User{}, false
+    }
+
+    return user, true
+}
+
+func (app *application) accountTwoFactor(w http.ResponseWriter, r *http.Request) {
+    user, ok := app.currentUser(w, r)
+    if !ok {
+        return
+    }
+
+    app.renderAccountTwoFactor(w, r, http.StatusOK, user, accountTwoFactorForm{})
+}
+
+func (app *application) accountTwoFactorEnablePost(w http.ResponseWriter, r *http.Request) {
+    user, ok := app.currentUser(w, r)
+    if !ok {
+        return
+    }
+
+    secret := app.sessionManager.GetString(r.Context(), "totpEnrollmentSecret")
+    if user.TOTPEnabled || secret == "" {
+        http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+        return
+    }
+
+    var form accountTwoFactorForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    // Check that the user has added the account to their app correctly before turning it on.
+    _, match := totp.Match(secret, strings.TrimSpace(form.Code), time.Now(), 1)
+
+    form.CheckField(validator.NotEmpty(form.Code), "code", "This field cannot be empty.")
+    form.CheckField(form.Code == "" || match, "code", "The code is incorrect. Check that your device's clock is right.")
+
+    if !form.Valid() {
+        app.renderAccountTwoFactor(w, r, http.StatusUnprocessableEntity, user, form)
+        return
+    }
+
+    codes, err := app.user.EnableTOTP(user.ID, secret)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Remove(r.Context(), "totpEnrollmentSecret")
+
+    // The recovery codes are only stored hashed, so this is the only time they can be shown.
+    user.TOTPEnabled = true
+
+    data := app.newTemplateData(r)
+    data.User = user
+    data.Form = accountTwoFactorForm{}
+    data.RecoveryCodes = codes
+
+    w.Header().Set("Cache-Control", "no-store")
+
+    app.render(w, r, http.StatusOK, "account_2fa.html", data)
+}
+
+func (app *application) accountTwoFactorDisablePost(w http.ResponseWriter, r *http.Request) {
+    user, ok := app.currentUser(w, r)
+    if !ok {
+        return
+    }
+
+    var form accountTwoFactorForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.CheckField(validator.NotEmpty(form.Password), "password", "This field cannot be empty.")
+
+    if form.Valid() {
+        _, err = app.user.Authenticate(user.Email, form.Password)
+        if errors.Is(err, models.ErrInvalidCredentials) {
+            form.AddFieldError("password", "Password is incorrect.")
+        } else if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+    }
+
+    if !form.Valid() {
+        app.renderAccountTwoFactor(w, r, http.StatusUnprocessableEntity, user, form)
+        return
+    }
+
+    err = app.user.DisableTOTP(user.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication has been turned off.")
+
+    http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+type accountPasswordUpdateForm struct {
+    CurrentPassword         string `form:"currentPassword"`
+    NewPassword             string `form:"newPassword"`
+    NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+    validator.Validator     `form:"-"`
+}
+
+func (app *application) accountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
+    data := app.newTemplateData(r)
+    data.Form = accountPasswordUpdateForm{}
+
+    app.render(w, r, http.StatusOK, "password.html", data)
+}
+
+func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http.Request) {
+    var form accountPasswordUpdateForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.CheckField(validator.NotEmpty(form.CurrentPassword), "currentPassword", "This field cannot be empty.")
+    form.CheckField(validator.NotEmpty(form.NewPassword), "newPassword", "This field cannot be empty.")
+    form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "This field must be at least 8 characters long.")
+    form.CheckField(validator.NotEmpty(form.NewPasswordConfirmation), "newPasswordConfirmation", "This field cannot be empty.")
+    form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "Passwords do not match.")
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Form = form
+
+        app.render(w, r, http.StatusUnprocessableEntity, "password.html", data)
+        return
+    }
+
+    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+    err = app.user.UpdatePassword(userID, form.CurrentPassword, form.NewPassword)
+    if err != nil {
+        if errors.Is(err, models.ErrInvalidCredentials) {
+            form.AddFieldError("currentPassword", "Current password is incorrect.")
+
+            data := app.newTemplateData(r)
+            data.Form = form
+
+            app.render(w, r, http.StatusUnprocessableEntity, "password.html", data)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Your password has been updated.")
+
+    http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+// snippetFromPath loads the snippet named by the {slug} or {id} wildcard in the request path. If
+// the snippet doesn't exist or the user isn't allowed to see it, a 404 Not Found response is sent
+// and ok is false. A 404 rather than a 403 is sent so that private snippets aren't disclosed.
+func (app *application) snippetFromPath(w http.ResponseWriter, r *http.Request) (snippet models.Snippet, ok bool) {
+    var err error
+
+    slug := r.PathValue("slug")
+    if slug != "" {
+        snippet, err = app.snippet.GetBySlug(slug)
+    } else {
+        id, convErr := strconv.Atoi(r.PathValue("id"))
+        if convErr != nil || id < 1 {
+            http.NotFound(w, r)
+            return models.Snippet{}, false
+        }
+
+        snippet, err = app.snippet.Get(id)
+    }
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return models.Snippet{}, false
+    }
+
+    if !snippet.VisibleTo(app.authenticatedUserID(r), slug != "") {
+        http.NotFound(w, r)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// snippetRedirect permanently redirects the numeric snippet URLs used before snippets had slugs
+// to the corresponding slug URLs. Snippets which the user could not see by their ID (including
+// all unlisted snippets of other users) are not found, so the redirect can't be used to
+// enumerate the slugs.
+func (app *application) snippetRedirect(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    target := snippet.URL()
+
+    if n := r.PathValue("n"); n != "" {
+        target += "/revision/" + n
+    }
+
+    http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// snippetLocked reports whether snippet is protected by a passphrase which the user hasn't entered
+// yet. The owner of a snippet never needs to unlock it.
+func (app *application) snippetLocked(r *http.Request, snippet models.Snippet) bool {
+    if !snippet.Protected || snippet.OwnedBy(app.authenticatedUserID(r)) {
+        return false
+    }
+
+    return !app.sessionManager.GetBool(r.Context(), unlockedSnippetKey(snippet.ID))
+}
+
+// unlockedSnippetKey returns the session key which records that the snippet id has been unlocked.
+func unlockedSnippetKey(id int) string {
+    return fmt.Sprintf("unlockedSnippet:%d", id)
+}
+
+// snippetViewData returns the data shown on the page of a snippet: its revisions, files, tags,
+// forks, stars and comments.
+func (app *application) snippetViewData(r *http.Request, snippet models.Snippet) (templateData, error) {
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+
+    var err error
+
+    data.Revisions, err = app.snippet.Revisions(snippet.ID)
+    if err != nil {
+        return data, err
+    }
+
+    data.Files, err = app.snippet.Files(snippet.ID)
+    if err != nil {
+        return data, err
+    }
+
+    data.Tags, err = app.snippet.Tags(snippet.ID)
+    if err != nil {
+        return data, err
+    }
+
+    data.Forks, err = app.snippet.ForkCount(snippet.ID)
+    if err != nil {
+        return data, err
+    }
+
+    // The original of an unlisted snippet is only linked for users who could find it without its
+    // slug, so that forking doesn't disclose the slug.
+    if snippet.ForkedFrom != 0 {
+        original, err := app.snippet.Get(snippet.ForkedFrom)
+        if err != nil && !errors.Is(err, models.ErrNoRecord) {
+            return data, err
+        }
+        if err == nil && original.VisibleTo(app.authenticatedUserID(r), false) {
+            data.ForkedFrom = original
+        }
+    }
+
+    data.Stars, data.IsStarred, err = app.snippet.Stars(snippet.ID, app.authenticatedUserID(r))
+    if err != nil {
+        return data, err
+    }
+
+    comments, err := app.comment.BySnippet(snippet.ID)
+    if err != nil {
+        return data, err
+    }
+    data.Comments = threadComments(comments)
+
+    return data, nil
+}
+
+func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    if app.snippetLocked(r, snippet) {
+        data := app.newTemplateData(r)
+        data.Snippet = snippet
+        data.Form = snippetUnlockForm{}
+
+        app.render(w, r, http.StatusOK, "snippet_unlock.html", data)
+        return
+    }
+
+    // The content of a burn after reading snippet is only sent in response to the POST request
+    // made by the "reveal" button, so that link previews and crawlers don't burn it.
+    if snippet.BurnAfterReading {
+        data := app.newTemplateData(r)
+        data.Snippet = snippet
+
+        app.render(w, r, http.StatusOK, "snippet_reveal.html", data)
+        return
+    }
+
+    data, err := app.snippetViewData(r, snippet)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+    data.Form = commentForm{}
+
+    app.render(w, r, http.StatusOK, "snippet_view.html", data)
+}
+
+func (app *application) snippetRevisionView(w http.ResponseWriter, r *http.Request) {
+    n, err := strconv.Atoi(r.PathValue("n"))
+    if err != nil || n < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    if snippet.BurnAfterReading {
+        http.NotFound(w, r)
+        return
+    }
+
+    if app.snippetLocked(r, snippet) {
+        http.Redirect(w, r, snippet.URL(), http.StatusSeeOther)
+        return
+    }
+
+    revision, err := app.snippet.Revision(snippet.ID, n)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    revisions, err := app.snippet.Revisions(snippet.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    // Show the snippet as it was at the requested revision.
+    snippet.Title = revision.Title
+    snippet.Content = revision.Content
+
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+    data.Revision = revision
+    data.Revisions = revisions
+
+    app.render(w, r, http.StatusOK, "snippet_view.html", data)
+}
+
+func (app *application) snippetRevealPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    if !snippet.BurnAfterReading || app.snippetLocked(r, snippet) {
+        http.Redirect(w, r, snippet.URL(), http.StatusSeeOther)
+        return
+    }
+
+    // The files are deleted together with the snippet, so they must be fetched first.
+    files, err := app.snippet.Files(snippet.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    // Fetch and delete the snippet in one step. If somebody else revealed it first, it's gone.
+    snippet, err = app.snippet.Burn(snippet.ID)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    w.Header().Set("Cache-Control", "no-store")
+
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+    data.Files = files
+
+    app.render(w, r, http.StatusOK, "snippet_view.html", data)
+}
+
+// rawSnippet loads the snippet named in the request path for the raw and download endpoints. Burn
+// after reading snippets and protected snippets which haven't been unlocked are not found.
+func (app *application) rawSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return models.Snippet{}, false
+    }
+
+    if snippet.BurnAfterReading || app.snippetLocked(r, snippet) {
+        http.NotFound(w, r)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// serveText writes content as plain text. http.ServeContent sets the Content-Length header and
+// answers conditional requests using the ETag header.
+func serveText(w http.ResponseWriter, r *http.Request, content string) {
+    // The ETag is derived from the content. No Last-Modified header is sent, because editing a
+    // snippet doesn't change its creation time, so If-Modified-Since could return stale content.
+    sum := sha256.Sum256([]byte(content))
+
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+
+    http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+}
+
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.rawSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    serveText(w, r, snippet.Content)
+}
+
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.rawSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    // FormatMediaType encodes file names which aren't plain ASCII as described in RFC 2231.
+    disposition := mime.FormatMediaType("attachment", map[string]string{"filename": snippetFilename(snippet)})
+    w.Header().Set("Content-Disposition", disposition)
+
+    serveText(w, r, snippet.Content)
+}
+
+func (app *application) snippetFileRaw(w http.ResponseWriter, r *http.Request) {
+    n, err := strconv.Atoi(r.PathValue("n"))
+    if err != nil || n < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    snippet, ok := app.rawSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    file, err := app.snippet.File(snippet.ID, n)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    serveText(w, r, file.Content)
+}
+
+// snippetZip sends the content and the files of a snippet as a zip archive. The content is stored
+// under the name it is downloaded as.
+func (app *application) snippetZip(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.rawSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    files, err := app.snippet.Files(snippet.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    filename := snippetFilename(snippet)
+    entries := append([]models.File{{Name: filename, Content: snippet.Content}}, files...)
+
+    // The archive is written to a buffer first, so that an error results in a 500 Internal
+    // Server Error response instead of a truncated archive.
+    buf := new(bytes.Buffer)
+    zw := zip.NewWriter(buf)
+    seen := make(map[string]bool)
+
+    for _, entry := range entries {
+        // A file can have the same name as the one the content is stored under.
+        name := entry.Name
+        base, ext := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+        for i := 2; seen[name]; i++ {
+            name = fmt.Sprintf("%s-%d%s", base, i, ext)
+        }
+        seen[name] = true
+
+        f, err := zw.CreateHeader(&zip.FileHeader{
+            Name:     name,
+            Method:   zip.Deflate,
+            Modified: snippet.Created,
+        })
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        _, err = io.WriteString(f, entry.Content)
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+    }
+
+    err = zw.Close()
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    archive := strings.TrimSuffix(filename, path.Ext(filename)) + ".zip"
+
+    w.Header().Set("Content-Type", "application/zip")
+    w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archive}))
+
+    buf.WriteTo(w)
+}
+
+type snippetUnlockForm struct {
+    Passphrase          string `form:"passphrase"`
+    validator.Validator `form:"-"`
+}
+
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    if !app.snippetLocked(r, snippet) {
+        http.Redirect(w, r, snippet.URL(), http.StatusSeeOther)
+        return
+    }
+
+    var form snippetUnlockForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.CheckField(validator.NotEmpty(form.Passphrase), "passphrase", "This field cannot be empty.")
+
+    status := http.StatusUnprocessableEntity
+
+    // Wrong passphrases are counted for each snippet rather than for each client, so that the
+    // passphrase can't be guessed by spreading the attempts over many sessions or addresses.
+    if form.Valid() && !app.unlockLimiter.Allow(snippet.ID) {
+        form.AddNonFieldError("Too many wrong passphrases. Please try again later.")
+        status = http.StatusTooManyRequests
+    }
+
+    if form.Valid() {
+        err = app.snippet.Unlock(snippet.ID, form.Passphrase)
+        if err != nil {
+            if errors.Is(err, models.ErrInvalidCredentials) {
+                app.unlockLimiter.Fail(snippet.ID)
+                form.AddFieldError("passphrase", "Passphrase is incorrect.")
+            } else if errors.Is(err, models.ErrNoRecord) {
+                http.NotFound(w, r)
+                return
+            } else {
+                app.serverError(w, r, err)
+                return
+            }
+        }
+    }
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Snippet = snippet
+        data.Form = form
+
+        app.render(w, r, status, "snippet_unlock.html", data)
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), unlockedSnippetKey(snippet.ID), true)
+
+    http.Redirect(w, r, snippet.URL(), http.StatusSeeOther)
+}
+
+type snippetCreateForm struct {
+    Title               string `form:"title"`
+    Content             string `form:"content"`
+    Visibility          string `form:"visibility"`
+    BurnAfterReading    bool   `form:"burnAfterReading"`
+    Passphrase          string `form:"passphrase"`
+    Language            string `form:"language"`  // Detected from the content if empty.
+    Format              string `form:"format"`
+    Expires             string `form:"expires"`
+    ExpiresAt           string `form:"expiresAt"`  // The date and time in UTC when Expires is "custom".
+    Files               []snippetFileForm `form:"files"`
+    Tags                string `form:"tags"`  // A comma-separated list of tags.
+    Action              string `form:"action"`  // The value of the button which submitted the form.
+    validator.Validator `form:"-"`
+}
+
+type snippetFileForm struct {
+    Name     string `form:"name"`
+    Language string `form:"language"`  // Detected from the name or content if empty.
+    Content  string `form:"content"`
+}
+
+// maxSnippetFiles is the number of files a snippet can hold besides its own content.
+const maxSnippetFiles = 10
+
+// maxSnippetTags is the number of tags a snippet can have.
+const maxSnippetTags = 5
+
+// tags returns the tags in the comma-separated list of the form in lower case, without blanks and
+// duplicates.
+func (form *snippetCreateForm) tags() []string {
+    var tags []string
+
+    for _, t := range strings.Split(form.Tags, ",") {
+        t = strings.ToLower(strings.TrimSpace(t))
+        if t != "" && !slices.Contains(tags, t) {
+            tags = append(tags, t)
+        }
+    }
+
+    return tags
+}
+
+// checkFiles validates the files of the form. The errors of a file are reported under field
+// names such as "files[0].name".
+func (form *snippetCreateForm) checkFiles() {
+    form.CheckField(len(form.Files) <= maxSnippetFiles, "files", fmt.Sprintf("A snippet cannot have more than %d files.", maxSnippetFiles))
+
+    names := make(map[string]bool)
+
+    for i, f := range form.Files {
+        field := fmt.Sprintf("files[%d]", i)
+
+        form.CheckField(validator.NotEmpty(f.Name), field+".name", "This field cannot be empty.")
+        form.CheckField(validator.MaxChars(f.Name, 100), field+".name", "This field cannot be more than 100 characters long.")
+        form.CheckField(validator.Match(f.Name, validator.FilenameRX), field+".name", "This field can only contain letters, digits, spaces, dots, dashes and underscores.")
+        form.CheckField(!names[f.Name], field+".name", "Another file has the same name.")
+        form.CheckField(f.Language == "" || highlight.Lookup(f.Language) != nil, field+".language", "This field must be one of the listed languages.")
+        form.CheckField(validator.NotEmpty(f.Content), field+".content", "This field cannot be empty.")
+
+        names[f.Name] = true
+    }
+}
+
+// customExpiryLayout is the format of the value of a datetime-local input.
+const customExpiryLayout = "2006-01-02T15:04"
+
+// expiryTime returns the time at which a snippet created at now expires, or the zero time if it
+// never expires. Field errors are added to the form if the chosen expiry is invalid.
+func (form *snippetCreateForm) expiryTime(now time.Time) time.Time {
+    switch form.Expires {
+    case "10m":
+        return now.Add(10 * time.Minute)
+    case "1h":
+        return now.Add(time.Hour)
+    case "1d":
+        return now.AddDate(0, 0, 1)
+    case "1w":
+        return now.AddDate(0, 0, 7)
+    case "1M":
+        return now.AddDate(0, 1, 0)
+    case "1y":
+        return now.AddDate(1, 0, 0)
+    case "never":
+        return time.Time{}
+    case "custom":
+        expires, err := time.Parse(customExpiryLayout, form.ExpiresAt)
+        if err != nil {
+            form.AddFieldError("expiresAt", "This field must be a valid date and time.")
+            return time.Time{}
+        }
+
+        form.CheckField(expires.After(now), "expiresAt", "This field must be in the future.")
+
+        return expires
+    default:
+        form.AddFieldError("expires", "This field must be one of the listed options.")
+        return time.Time{}
+    }
+}
+
+func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
+    data := app.newTemplateData(r)
+    data.Form = snippetCreateForm{
+        Visibility: models.VisibilityPublic,
+        Format:     models.FormatPlain,
+        Expires:    "1y",
+    }
+
+    app.render(w, r, http.StatusOK, "snippet_create.html", data)
+}
+
+func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
+    form := snippetCreateForm{}
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    // The buttons which add and remove files submit the form too. The form is shown again with
+    // the changed list of files, without being validated.
+    if form.Action == "addFile" || strings.HasPrefix(form.Action, "removeFile:") {
+        if form.Action == "addFile" {
+            if len(form.Files) < maxSnippetFiles {
+                form.Files = append(form.Files, snippetFileForm{})
+            }
+        } else if i, err := strconv.Atoi(strings.TrimPrefix(form.Action, "removeFile:")); err == nil && i >= 0 && i < len(form.Files) {
+            form.Files = slices.Delete(form.Files, i, i+1)
+        }
+
+        data := app.newTemplateData(r)
+        data.Form = form
+        app.render(w, r, http.StatusOK, "snippet_create.html", data)
+        return
+    }
+
+    form.CheckField(validator.NotEmpty(form.Title), "title", "This field cannot be empty.")
+    form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long.")
+    form.CheckField(validator.NotEmpty(form.Content), "content", "This field cannot be empty.")
+    form.checkFiles()
+    form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must be public, unlisted or private.")
+    form.CheckField(validator.PermittedValue(form.Format, models.FormatPlain, models.FormatMarkdown), "format", "This field must be plain text or Markdown.")
+    form.CheckField(form.Language == "" || highlight.Lookup(form.Language) != nil, "language", "This field must be one of the listed languages.")
+    form.CheckField(validator.MaxChars(form.Passphrase, 72), "passphrase", "This field cannot be more than 72 characters long.")
+
+    tags := form.tags()
+    form.CheckField(validator.MaxItems(tags, maxSnippetTags), "tags", fmt.Sprintf("This field cannot have more than %d tags.", maxSnippetTags))
+    form.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags can only contain letters, digits, \"+\", \".\" and \"-\", and be up to 30 characters long.")
+
+    expires := form.expiryTime(time.Now().UTC())
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Form = form
+        app.render(w, r, http.StatusUnprocessableEntity, "snippet_create.html", data)
+        return
+    }
+
+    if form.Language == "" {
+        form.Language = highlight.Text
+
+        // Markdown snippets aren't highlighted, so only the language of plain text is detected.
+        if form.Format == models.FormatPlain {
+            form.Language = highlight.Detect(form.Content)
+        }
+    }
+
+    var files []models.File
+
+    for _, f := range form.Files {
+        if f.Language == "" {
+            f.Language = highlight.DetectFile(f.Name, f.Content)
+        }
+
+        files = append(files, models.File{Name: f.Name, Language: f.Language, Content: f.Content})
+    }
+
+    userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+    slug, err := app.snippet.Insert(models.NewSnippet{
+        UserID:           userID,
+        Title:            form.Title,
+        Content:          form.Content,
+        Visibility:       form.Visibility,
+        BurnAfterReading: form.BurnAfterReading,
+        Passphrase:       form.Passphrase,
+        Language:         form.Language,
+        Format:           form.Format,
+        Files:            files,
+        Tags:             tags,
+        Expires:          expires,
+    })
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created.")
+
+    http.Redirect(w, r, "/s/"+slug, http.StatusSeeOther)
+}
+
+// snippetForkPost creates a copy of a snippet owned by the current user, which records the snippet
+// it was forked from. The copy has the title, content, files, tags and visibility of the current
+// revision of the original, but no passphrase and no expiry time, so that it is kept until its
+// new owner deletes it.
+func (app *application) snippetForkPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    // The content of burn after reading and locked snippets can't be copied without being read.
+    if snippet.BurnAfterReading || app.snippetLocked(r, snippet) {
+        http.NotFound(w, r)
+        return
+    }
+
+    files, err := app.snippet.Files(snippet.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    tags, err := app.snippet.Tags(snippet.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    slug, err := app.snippet.Insert(models.NewSnippet{
+        UserID:     app.authenticatedUserID(r),
+        Title:      snippet.Title,
+        Content:    snippet.Content,
+        Visibility: snippet.Visibility,
+        Language:   snippet.Language,
+        Format:     snippet.Format,
+        Files:      files,
+        Tags:       tags,
+        ForkedFrom: snippet.ID,
+    })
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully forked.")
+
+    http.Redirect(w, r, "/s/"+slug, http.StatusSeeOther)
+}
+
+// snippetStarPost stars the snippet for the current user, or removes their star if they have
+// already starred it.
+func (app *application) snippetStarPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    if snippet.BurnAfterReading {
+        http.NotFound(w, r)
+        return
+    }
+
+    userID := app.authenticatedUserID(r)
+
+    _, starred, err := app.snippet.Stars(snippet.ID, userID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    if starred {
+        err = app.snippet.Unstar(snippet.ID, userID)
+    } else {
+        err = app.snippet.Star(snippet.ID, userID)
+    }
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    http.Redirect(w, r, snippet.URL(), http.StatusSeeOther)
+}
+
+type commentForm struct {
+    Content             string `form:"content"`
+    Line                int    `form:"line"`  // The line the comment is about, or 0.
+    ParentID            int    `form:"parentID"`  // The ID of the comment replied to, or 0.
+    validator.Validator `form:"-"`
+}
+
+// lineCount returns the number of lines of s, numbered as they are by highlight.HTML.
+func lineCount(s string) int {
+    s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+
+    return strings.Count(s, "\n") + 1
+}
+
+func (app *application) snippetCommentPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    if snippet.BurnAfterReading || app.snippetLocked(r, snippet) {
+        http.NotFound(w, r)
+        return
+    }
+
+    var form commentForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    // A reply must be to a comment on the same snippet which hasn't been deleted.
+    if form.ParentID != 0 {
+        parent, err := app.comment.Get(form.ParentID)
+        if err != nil && !errors.Is(err, models.ErrNoRecord) {
+            app.serverError(w, r, err)
+            return
+        }
+        if err != nil || parent.SnippetID != snippet.ID || !parent.Deleted.IsZero() {
+            app.clientError(w, http.StatusBadRequest)
+            return
+        }
+    }
+
+    form.CheckField(validator.NotEmpty(form.Content), "content", "This field cannot be empty.")
+    form.CheckField(validator.MaxChars(form.Content, 2000), "content", "This field cannot be more than 2000 characters long.")
+
+    // Markdown snippets are shown without line numbers, so their lines can't be referred to.
+    if snippet.Format == models.FormatMarkdown {
+        form.CheckField(form.Line == 0, "line", "Comments on Markdown snippets cannot refer to a line.")
+    } else {
+        form.CheckField(form.Line >= 0 && form.Line <= lineCount(snippet.Content), "line", fmt.Sprintf("This field must be a line of the snippet, from 1 to %d.", lineCount(snippet.Content)))
+    }
+
     if !form.Valid() {
-        data := app.newTemplateData(r)
+        data, err := app.snippetViewData(r, snippet)
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
         data.Form = form
-        app.render(w, r, http.StatusUnprocessableEntity, "snippet_create.html", data)
+
+        app.render(w, r, http.StatusUnprocessableEntity, "snippet_view.html", data)
         return
     }
 
-    id, err := app.snippet.Insert(form.Title, form.Content, form.Expires)
+    id, err := app.comment.Insert(snippet.ID, app.authenticatedUserID(r), form.ParentID, form.Line, form.Content)
     if err != nil {
         app.serverError(w, r, err)
         return
     }
 
-    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created.")
+    http.Redirect(w, r, fmt.Sprintf("%s#C%d", snippet.URL(), id), http.StatusSeeOther)
+}
+
+// commentDeletePost deletes a comment. Only the author of the comment and the owner of the snippet
+// may delete it.
+func (app *application) commentDeletePost(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil || id < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    comment, err := app.comment.Get(id)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    snippet, err := app.snippet.Get(comment.SnippetID)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    err = app.comment.Delete(id, app.authenticatedUserID(r))
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Comment deleted.")
+
+    http.Redirect(w, r, snippet.URL()+"#comments", http.StatusSeeOther)
+}
+
+type snippetEditForm struct {
+    Title               string `form:"title"`
+    Content             string `form:"content"`
+    validator.Validator `form:"-"`
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    // Only the owner of a snippet may edit it.
+    if !snippet.OwnedBy(app.authenticatedUserID(r)) {
+        app.clientError(w, http.StatusForbidden)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+    data.Form = snippetEditForm{
+        Title:   snippet.Title,
+        Content: snippet.Content,
+    }
+
+    app.render(w, r, http.StatusOK, "snippet_edit.html", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.snippetFromPath(w, r)
+    if !ok {
+        return
+    }
+
+    userID := app.authenticatedUserID(r)
+
+    if !snippet.OwnedBy(userID) {
+        app.clientError(w, http.StatusForbidden)
+        return
+    }
+
+    var form snippetEditForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.CheckField(validator.NotEmpty(form.Title), "title", "This field cannot be empty.")
+    form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long.")
+    form.CheckField(validator.NotEmpty(form.Content), "content", "This field cannot be empty.")
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Snippet = snippet
+        data.Form = form
+        app.render(w, r, http.StatusUnprocessableEntity, "snippet_edit.html", data)
+        return
+    }
+
+    err = app.snippet.Update(snippet.ID, userID, form.Title, form.Content)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated.")
+
+    http.Redirect(w, r, snippet.URL(), http.StatusSeeOther)
+}
+
+// diffSnippets loads the two snippets named by the "a" and "b" query string parameters of a diff
+// request. A snippet can be named by its slug, which lets users diff the unlisted snippets they can
+// view, or by its ID. If they can't be loaded, an error response is sent and ok is false.
+func (app *application) diffSnippets(w http.ResponseWriter, r *http.Request) (a, b models.Snippet, ok bool) {
+    var snippets [2]models.Snippet
+
+    for i, name := range []string{"a", "b"} {
+        value := r.URL.Query().Get(name)
+        if value == "" {
+            app.clientError(w, http.StatusBadRequest)
+            return a, b, false
+        }
+
+        snippet, err := app.snippet.GetBySlug(value)
+        bySlug := err == nil
+
+        if errors.Is(err, models.ErrNoRecord) {
+            id, convErr := strconv.Atoi(value)
+            if convErr == nil && id >= 1 {
+                snippet, err = app.snippet.Get(id)
+            }
+        }
+        if err != nil {
+            if errors.Is(err, models.ErrNoRecord) {
+                http.NotFound(w, r)
+            } else {
+                app.serverError(w, r, err)
+            }
+            return a, b, false
+        }
+
+        // Burn after reading snippets can only be read through the reveal button, and protected
+        // snippets only once they have been unlocked.
+        if !snippet.VisibleTo(app.authenticatedUserID(r), bySlug) || snippet.BurnAfterReading || app.snippetLocked(r, snippet) {
+            http.NotFound(w, r)
+            return a, b, false
+        }
+
+        snippets[i] = snippet
+    }
+
+    return snippets[0], snippets[1], true
+}
+
+func (app *application) diffView(w http.ResponseWriter, r *http.Request) {
+    a, b, ok := app.diffSnippets(w, r)
+    if !ok {
+        return
+    }
+
+    view := r.URL.Query().Get("view")
+    if view != "split" {
+        view = "unified"
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippets = []models.Snippet{a, b}
+    data.Hunks = diff.Hunks(diff.Lines(a.Content, b.Content), 3)
+    data.DiffView = view
+
+    app.render(w, r, http.StatusOK, "diff.html", data)
+}
+
+func (app *application) diffDownload(w http.ResponseWriter, r *http.Request) {
+    a, b, ok := app.diffSnippets(w, r)
+    if !ok {
+        return
+    }
+
+    oldName := fmt.Sprintf("snippet-%d", a.ID)
+    newName := fmt.Sprintf("snippet-%d", b.ID)
+    hunks := diff.Hunks(diff.Lines(a.Content, b.Content), 3)
+
+    w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+    w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.diff"`, oldName, newName))
+
+    w.Write([]byte(diff.Unified(oldName, newName, hunks)))
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil || id < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    err = app.snippet.Delete(id, app.authenticatedUserID(r))
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet moved to the trash.")
+
+    http.Redirect(w, r, "/account/trash", http.StatusSeeOther)
+}
+
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil || id < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    err = app.snippet.Restore(id, app.authenticatedUserID(r))
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully restored.")
 
     http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 }
+
+func (app *application) snippetPurgePost(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil || id < 1 {
+        http.NotFound(w, r)
+        return
+    }
+
+    err = app.snippet.Purge(id, app.authenticatedUserID(r))
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            http.NotFound(w, r)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet permanently deleted.")
+
+    http.Redirect(w, r, "/account/trash", http.StatusSeeOther)
+}
+
+func (app *application) accountTrash(w http.ResponseWriter, r *http.Request) {
+    snippets, err := app.snippet.Trash(app.authenticatedUserID(r))
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippets = snippets
+
+    app.render(w, r, http.StatusOK, "trash.html", data)
+}
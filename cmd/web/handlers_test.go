@@ -1,10 +1,22 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"regexp"
 	"snippetbox/internal/assert"
+	"snippetbox/internal/highlight"
+	"snippetbox/internal/mailer"
+	"snippetbox/internal/totp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestPing(t *testing.T) {
@@ -34,11 +46,28 @@ func TestSnippetView(t *testing.T) {
         expectBody string
     }{
         {
-            name:       "Valid ID",
-            urlPath:    "/snippet/view/1",
+            name:       "Valid slug",
+            urlPath:    "/s/pQ7nF2kLm0A",
             expectCode: http.StatusOK,
             expectBody: "An old silent pond...",
         },
+        {
+            name:       "Tags",
+            urlPath:    "/s/pQ7nF2kLm0A",
+            expectCode: http.StatusOK,
+            expectBody: `<a class="tag" href="/tag/haiku">haiku</a><a class="tag" href="/tag/poetry">poetry</a>`,
+        },
+        {
+            name:       "Non-existent slug",
+            urlPath:    "/s/AAAAAAAAAAA",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Valid ID",
+            urlPath:    "/snippet/view/1",
+            expectCode: http.StatusMovedPermanently,
+            expectBody: `<a href="/s/pQ7nF2kLm0A">`,
+        },
         {
             name:       "Non-existent ID",
             urlPath:    "/snippet/view/2",
@@ -64,6 +93,28 @@ func TestSnippetView(t *testing.T) {
             urlPath:    "/snippet/view/",
             expectCode: http.StatusNotFound,
         },
+        {
+            name:       "Past revision",
+            urlPath:    "/s/pQ7nF2kLm0A/revision/1",
+            expectCode: http.StatusOK,
+            expectBody: "An old pond...",
+        },
+        {
+            name:       "Non-existent revision",
+            urlPath:    "/s/pQ7nF2kLm0A/revision/3",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Invalid revision",
+            urlPath:    "/s/pQ7nF2kLm0A/revision/foo",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Past revision by ID",
+            urlPath:    "/snippet/view/1/revision/1",
+            expectCode: http.StatusMovedPermanently,
+            expectBody: `<a href="/s/pQ7nF2kLm0A/revision/1">`,
+        },
     }
 
     for _, tc := range tests {
@@ -193,3 +244,2062 @@ func TestUserSignup(t *testing.T) {
         })
     }
 }
+
+func TestUserSignupEmail(t *testing.T) {
+    var buf bytes.Buffer
+
+    app := newTestApplication(t)
+    app.mailer = mailer.NewWriter(&buf, "no-reply@example.com")
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    _, _, body := ts.get(t, "/user/signup")
+
+    form := url.Values{}
+    form.Add("name", "Bob")
+    form.Add("email", "bob@example.com")
+    form.Add("password", "validPa$$word")
+    form.Add("csrf_token", extractCSRFToken(t, body))
+
+    code, _, _ := ts.postForm(t, "/user/signup", form)
+
+    assert.Equal(t, code, http.StatusSeeOther)
+    assert.StringContains(t, buf.String(), "To: \"Bob\" <bob@example.com>")
+    assert.StringContains(t, buf.String(), "https://snippetbox.example.com/user/verify?token=")
+}
+
+func TestUserLoginUnverified(t *testing.T) {
+    var buf bytes.Buffer
+
+    app := newTestApplication(t)
+    app.mailer = mailer.NewWriter(&buf, "no-reply@example.com")
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    _, _, body := ts.get(t, "/user/login")
+
+    form := url.Values{}
+    form.Add("email", "bob@example.com")
+    form.Add("password", "pa$$word")
+    form.Add("csrf_token", extractCSRFToken(t, body))
+
+    code, _, body := ts.postForm(t, "/user/login", form)
+
+    assert.Equal(t, code, http.StatusUnprocessableEntity)
+    assert.StringContains(t, body, "You need to verify your email address before you can login.")
+    assert.StringContains(t, buf.String(), "/user/verify?token=")
+
+    // The user isn't logged in.
+    code, header, _ := ts.get(t, "/account/view")
+
+    assert.Equal(t, code, http.StatusSeeOther)
+    assert.Equal(t, header.Get("Location"), "/user/login")
+}
+
+func TestUserVerify(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tokenFor := func(value string, expires time.Time) string {
+        return url.QueryEscape(app.tokens.Sign(value, expires))
+    }
+    valid := time.Now().Add(time.Hour)
+
+    tests := []struct {
+        name        string
+        token       string
+        expectFlash string
+    }{
+        {
+            name: "Valid token",
+            token: tokenFor("verify-email:2:bob@example.com", valid),
+            expectFlash: "Your email address has been verified.",
+        },
+        {
+            name: "Expired token",
+            token: tokenFor("verify-email:2:bob@example.com", time.Now().Add(-time.Minute)),
+            expectFlash: "That verification link is invalid or has expired.",
+        },
+        {
+            name: "Changed email address",
+            token: tokenFor("verify-email:2:robert@example.com", valid),
+            expectFlash: "That verification link is invalid or has expired.",
+        },
+        {
+            name: "Other purpose",
+            token: tokenFor("reset-password:2:bob@example.com", valid),
+            expectFlash: "That verification link is invalid or has expired.",
+        },
+        {
+            name: "Tampered token",
+            token: tokenFor("verify-email:2:bob@example.com", valid) + "x",
+            expectFlash: "That verification link is invalid or has expired.",
+        },
+        {
+            name: "Missing token",
+            token: "",
+            expectFlash: "That verification link is invalid or has expired.",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, header, _ := ts.get(t, "/user/verify?token=" + tc.token)
+
+            assert.Equal(t, code, http.StatusSeeOther)
+            assert.Equal(t, header.Get("Location"), "/user/login")
+
+            _, _, body := ts.get(t, "/user/login")
+
+            assert.StringContains(t, body, tc.expectFlash)
+        })
+    }
+}
+
+func TestUserPasswordForgot(t *testing.T) {
+    var buf bytes.Buffer
+
+    app := newTestApplication(t)
+    app.mailer = mailer.NewWriter(&buf, "no-reply@example.com")
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    const flash = "If an account uses that email address, we&#39;ve emailed it a link to reset the password."
+
+    tests := []struct {
+        name        string
+        email       string
+        expectEmail bool
+    }{
+        {
+            name: "Existing account",
+            email: "alice@example.com",
+            expectEmail: true,
+        },
+        {
+            name: "No such account",
+            email: "nobody@example.com",
+            expectEmail: false,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            buf.Reset()
+
+            _, _, body := ts.get(t, "/user/password/forgot")
+
+            form := url.Values{}
+            form.Add("email", tc.email)
+            form.Add("csrf_token", extractCSRFToken(t, body))
+
+            code, header, _ := ts.postForm(t, "/user/password/forgot", form)
+
+            // The response is the same whether or not the account exists.
+            assert.Equal(t, code, http.StatusSeeOther)
+            assert.Equal(t, header.Get("Location"), "/user/login")
+
+            _, _, body = ts.get(t, "/user/login")
+            assert.StringContains(t, body, flash)
+
+            app.wg.Wait()
+            assert.Equal(t, strings.Contains(buf.String(), "https://snippetbox.example.com/user/password/reset/"), tc.expectEmail)
+        })
+    }
+
+    t.Run("Invalid email", func(t *testing.T) {
+        _, _, body := ts.get(t, "/user/password/forgot")
+
+        form := url.Values{}
+        form.Add("email", "alice@example.")
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, _, _ := ts.postForm(t, "/user/password/forgot", form)
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+    })
+}
+
+func TestUserPasswordReset(t *testing.T) {
+    app := newTestApplication(t)
+
+    token, err := app.passwordReset.Insert(1, time.Hour)
+    assert.NilError(t, err)
+
+    t.Run("Invalid token", func(t *testing.T) {
+        ts := newTestServer(t, app.routes())
+        defer ts.Close()
+
+        code, header, _ := ts.get(t, "/user/password/reset/wrong")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/password/forgot")
+
+        _, _, body := ts.get(t, "/user/password/forgot")
+        assert.StringContains(t, body, "That password reset link is invalid or has expired.")
+    })
+
+    t.Run("Mismatched passwords", func(t *testing.T) {
+        ts := newTestServer(t, app.routes())
+        defer ts.Close()
+
+        code, _, body := ts.get(t, "/user/password/reset/" + token)
+        assert.Equal(t, code, http.StatusOK)
+
+        form := url.Values{}
+        form.Add("newPassword", "n3wPa$$word")
+        form.Add("newPasswordConfirmation", "otherPa$$word")
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, _, body = ts.postForm(t, "/user/password/reset/" + token, form)
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "Passwords do not match.")
+    })
+
+    t.Run("Valid submission", func(t *testing.T) {
+        // Alice is logged in on another device.
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        other.login(t)
+
+        code, _, _ := other.get(t, "/account/view")
+        assert.Equal(t, code, http.StatusOK)
+
+        // Alice is also logged in with a session which isn't in the session index, like the
+        // sessions created before the index existed.
+        unindexed := newTestServer(t, app.routes())
+        defer unindexed.Close()
+
+        ctx, err := app.sessionManager.Load(context.Background(), "")
+        assert.NilError(t, err)
+
+        app.sessionManager.Put(ctx, "authenticatedUserID", 1)
+
+        sessionToken, _, err := app.sessionManager.Commit(ctx)
+        assert.NilError(t, err)
+
+        u, err := url.Parse(unindexed.URL)
+        assert.NilError(t, err)
+
+        unindexed.Client().Jar.SetCookies(u, []*http.Cookie{{Name: app.sessionManager.Cookie.Name, Value: sessionToken}})
+
+        code, _, _ = unindexed.get(t, "/account/view")
+        assert.Equal(t, code, http.StatusOK)
+
+        ts := newTestServer(t, app.routes())
+        defer ts.Close()
+
+        _, _, body := ts.get(t, "/user/password/reset/" + token)
+
+        form := url.Values{}
+        form.Add("newPassword", "n3wPa$$word")
+        form.Add("newPasswordConfirmation", "n3wPa$$word")
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, header, _ := ts.postForm(t, "/user/password/reset/" + token, form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+
+        // The other sessions have been logged out.
+        code, header, _ = other.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+
+        code, header, _ = unindexed.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+    })
+}
+
+func TestUserLoginThrottle(t *testing.T) {
+    var logs, mails bytes.Buffer
+
+    app := newTestApplication(t)
+    app.logger = slog.New(slog.NewTextHandler(&logs, nil))
+    app.mailer = mailer.NewWriter(&mails, "no-reply@example.com")
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    logIn := func(t *testing.T, email, password string) (int, http.Header, string) {
+        _, _, body := ts.get(t, "/user/login")
+
+        form := url.Values{}
+        form.Add("email", email)
+        form.Add("password", password)
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        return ts.postForm(t, "/user/login", form)
+    }
+
+    t.Run("Blocked account", func(t *testing.T) {
+        code, header, body := logIn(t, "locked@example.com", "pa$$word")
+
+        assert.Equal(t, code, http.StatusTooManyRequests)
+        assert.Equal(t, header.Get("Retry-After"), "600")
+        assert.StringContains(t, body, "Too many failed login attempts. Please try again in 10 minutes.")
+    })
+
+    t.Run("Lockout", func(t *testing.T) {
+        logs.Reset()
+        mails.Reset()
+
+        code, _, body := logIn(t, "alice@example.com", "wrongPa$$word")
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "Email or password is incorrect.")
+        assert.StringContains(t, logs.String(), `msg="login locked out" subject=account:alice@example.com failures=10`)
+
+        app.wg.Wait()
+        assert.StringContains(t, mails.String(), "To: \"Alice\" <alice@example.com>")
+        assert.StringContains(t, mails.String(), "Subject: Logins to your account have been locked")
+    })
+
+    t.Run("Lockout after an expired lockout", func(t *testing.T) {
+        logs.Reset()
+        mails.Reset()
+
+        code, _, _ := logIn(t, "bob@example.com", "wrongPa$$word")
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, logs.String(), `msg="login locked out" subject=account:bob@example.com failures=11`)
+
+        // The owner has already been told about the lockout.
+        app.wg.Wait()
+        assert.Equal(t, mails.Len(), 0)
+    })
+
+    t.Run("Free failure", func(t *testing.T) {
+        logs.Reset()
+        mails.Reset()
+
+        code, _, _ := logIn(t, "nobody@example.com", "wrongPa$$word")
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.Equal(t, strings.Contains(logs.String(), "login locked out"), false)
+
+        app.wg.Wait()
+        assert.Equal(t, mails.Len(), 0)
+    })
+}
+
+func TestUserLoginTwoFactor(t *testing.T) {
+    // logInWithPassword enters Carol's password, which isn't enough to log her in.
+    logInWithPassword := func(t *testing.T, ts *testServer) {
+        _, _, body := ts.get(t, "/user/login")
+
+        form := url.Values{}
+        form.Add("email", "carol@example.com")
+        form.Add("password", "pa$$word")
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, header, _ := ts.postForm(t, "/user/login", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login/2fa")
+
+        code, header, _ = ts.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+    }
+
+    submitCode := func(t *testing.T, ts *testServer, otp string) (int, http.Header, string) {
+        _, _, body := ts.get(t, "/user/login/2fa")
+
+        form := url.Values{}
+        form.Add("code", otp)
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        return ts.postForm(t, "/user/login/2fa", form)
+    }
+
+    tests := []struct {
+        name       string
+        code       string
+        expectCode int
+    }{
+        {
+            name: "One-time password",
+            code: "123456",
+            expectCode: http.StatusSeeOther,
+        },
+        {
+            name: "Recovery code",
+            code: "abcd-efgh-ijkl-mnop",
+            expectCode: http.StatusSeeOther,
+        },
+        {
+            name: "Wrong one-time password",
+            code: "654321",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name: "Wrong recovery code",
+            code: "abcd-efgh-ijkl-mnoq",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name: "Empty code",
+            code: "",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            app := newTestApplication(t)
+            ts := newTestServer(t, app.routes())
+            defer ts.Close()
+
+            logInWithPassword(t, ts)
+
+            code, _, _ := submitCode(t, ts, tc.code)
+            assert.Equal(t, code, tc.expectCode)
+
+            code, _, _ = ts.get(t, "/account/view")
+            if tc.expectCode == http.StatusSeeOther {
+                assert.Equal(t, code, http.StatusOK)
+            } else {
+                assert.Equal(t, code, http.StatusSeeOther)
+            }
+        })
+    }
+
+    t.Run("Without password", func(t *testing.T) {
+        app := newTestApplication(t)
+        ts := newTestServer(t, app.routes())
+        defer ts.Close()
+
+        code, header, _ := ts.get(t, "/user/login/2fa")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+    })
+
+    t.Run("Too many attempts", func(t *testing.T) {
+        app := newTestApplication(t)
+        ts := newTestServer(t, app.routes())
+        defer ts.Close()
+
+        logInWithPassword(t, ts)
+
+        for i := 0; i < 5; i++ {
+            code, _, _ := submitCode(t, ts, "000000")
+            assert.Equal(t, code, http.StatusUnprocessableEntity)
+        }
+
+        // Even the right code is refused now.
+        code, _, body := submitCode(t, ts, "123456")
+
+        assert.Equal(t, code, http.StatusTooManyRequests)
+        assert.StringContains(t, body, "Too many incorrect codes.")
+    })
+}
+
+func TestAccountTwoFactor(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    ts.login(t)
+
+    code, header, body := ts.get(t, "/account/2fa")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.Equal(t, header.Get("Cache-Control"), "no-store")
+    assert.StringContains(t, body, `<div class="qrcode"><svg xmlns="http://www.w3.org/2000/svg"`)
+
+    secret := regexp.MustCompile(`<code>([A-Z2-7]{32})</code>`).FindStringSubmatch(body)
+    if secret == nil {
+        t.Fatal("no secret found in body")
+    }
+
+    // The same secret is offered until the user confirms it.
+    _, _, body = ts.get(t, "/account/2fa")
+    assert.StringContains(t, body, secret[0])
+
+    t.Run("Wrong code", func(t *testing.T) {
+        form := url.Values{}
+        form.Add("code", "000000")
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, _, body := ts.postForm(t, "/account/2fa/enable", form)
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "The code is incorrect.")
+    })
+
+    t.Run("Right code", func(t *testing.T) {
+        otp, err := totp.Code(secret[1], totp.Step(time.Now()))
+        assert.NilError(t, err)
+
+        form := url.Values{}
+        form.Add("code", otp)
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, header, body := ts.postForm(t, "/account/2fa/enable", form)
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.Equal(t, header.Get("Cache-Control"), "no-store")
+        assert.StringContains(t, body, "<li><code>abcd-efgh-ijkl-mnop</code></li>")
+    })
+}
+
+func TestAccountView(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    t.Run("Unauthenticated", func(t *testing.T) {
+        code, header, _ := ts.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+    })
+
+    t.Run("Authenticated", func(t *testing.T) {
+        ts.login(t)
+
+        code, _, body := ts.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, "My Snippets")
+        assert.StringContains(t, body, `<a href="/s/pQ7nF2kLm0A">An old silent pond</a>`)
+        assert.StringContains(t, body, "Starred Snippets")
+        assert.StringContains(t, body, `<a href="/s/Md8kT2vRw5N">Release notes</a>`)
+    })
+}
+
+func TestAccountSessions(t *testing.T) {
+    app := newTestApplication(t)
+
+    // Log in as Alice with three clients. Their sessions get the IDs 1, 2 and 3.
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+    csrfToken := ts.login(t)
+
+    other := newTestServer(t, app.routes())
+    defer other.Close()
+    other.login(t)
+
+    third := newTestServer(t, app.routes())
+    defer third.Close()
+    third.login(t)
+
+    code, _, body := ts.get(t, "/account/view")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "Active Sessions")
+    assert.StringContains(t, body, `<td title="Go-http-client/1.1">Go-http-client/1.1 <span class="muted">(this session)</span></td>`)
+    assert.StringContains(t, body, "<td>127.0.0.1</td>")
+    assert.StringContains(t, body, `action="/account/session/revoke/3"`)
+    assert.StringContains(t, body, "Sign out everywhere else")
+
+    t.Run("Sign out another session", func(t *testing.T) {
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := ts.postForm(t, "/account/session/revoke/2", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/account/view")
+
+        code, header, _ = other.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+
+        // The session can't be signed out twice.
+        code, _, _ = ts.postForm(t, "/account/session/revoke/2", form)
+
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+
+    t.Run("Sign out everywhere else", func(t *testing.T) {
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := ts.postForm(t, "/account/session/revoke-others", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/account/view")
+
+        code, header, _ = third.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+
+        code, _, body := ts.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, "You&#39;ve been signed out everywhere else.")
+        assert.Equal(t, strings.Contains(body, `action="/account/session/revoke/3"`), false)
+        assert.Equal(t, strings.Contains(body, "Sign out everywhere else"), false)
+    })
+
+    t.Run("Sign out this session", func(t *testing.T) {
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := ts.postForm(t, "/account/session/revoke/1", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/")
+
+        code, header, _ = ts.get(t, "/account/view")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/user/login")
+    })
+}
+
+
+func TestSnippetEdit(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    csrfToken := ts.login(t)
+
+    code, _, body := ts.get(t, "/snippet/edit/1")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, `<form action="/snippet/edit/1" method="POST" novalidate>`)
+
+    tests := []struct {
+        name         string
+        urlPath      string
+        title        string
+        content      string
+        expectCode   int
+        expectHeader string
+    }{
+        {
+            name:         "Valid submission",
+            urlPath:      "/snippet/edit/1",
+            title:        "An old silent pond",
+            content:      "A frog jumps into the pond",
+            expectCode:   http.StatusSeeOther,
+            expectHeader: "/s/pQ7nF2kLm0A",
+        },
+        {
+            name:       "Empty content",
+            urlPath:    "/snippet/edit/1",
+            title:      "An old silent pond",
+            content:    "",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:       "Non-existent ID",
+            urlPath:    "/snippet/edit/2",
+            title:      "An old silent pond",
+            content:    "A frog jumps into the pond",
+            expectCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("title", tc.title)
+            form.Add("content", tc.content)
+            form.Add("csrf_token", csrfToken)
+
+            code, header, _ := ts.postForm(t, tc.urlPath, form)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectHeader != "" {
+                assert.Equal(t, header.Get("Location"), tc.expectHeader)
+            }
+        })
+    }
+}
+func TestDiff(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name       string
+        urlPath    string
+        expectCode int
+        expectBody string
+    }{
+        {
+            name:       "Unified view",
+            urlPath:    "/diff?a=1&b=1",
+            expectCode: http.StatusOK,
+            expectBody: "The snippets are identical.",
+        },
+        {
+            name:       "Split view",
+            urlPath:    "/diff?a=1&b=1&view=split",
+            expectCode: http.StatusOK,
+            expectBody: "Unified view",
+        },
+        {
+            name:       "Non-existent ID",
+            urlPath:    "/diff?a=1&b=2",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Slugs",
+            urlPath:    "/diff?a=pQ7nF2kLm0A&b=Fk2uL7nPq1Z",
+            expectCode: http.StatusOK,
+            expectBody: `<a href="/diff?a=pQ7nF2kLm0A&b=Fk2uL7nPq1Z&view=split">Split view</a>`,
+        },
+        {
+            name:       "Unlisted snippet by slug",
+            urlPath:    "/diff?a=Xq3_aZ8rT0w&b=Fk2uL7nPq1Z",
+            expectCode: http.StatusOK,
+            expectBody: "The snippets are identical.",
+        },
+        {
+            name:       "Unlisted snippet by ID",
+            urlPath:    "/diff?a=5&b=9",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Private snippet by slug",
+            urlPath:    "/diff?a=1&b=Zr4-uW1cVbE",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Missing ID",
+            urlPath:    "/diff?a=1",
+            expectCode: http.StatusBadRequest,
+        },
+        {
+            name:       "Download",
+            urlPath:    "/diff/download?a=1&b=1",
+            expectCode: http.StatusOK,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tc.urlPath)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+
+    t.Run("Download headers", func(t *testing.T) {
+        _, header, _ := ts.get(t, "/diff/download?a=1&b=1")
+
+        assert.Equal(t, header.Get("Content-Type"), "text/x-diff; charset=utf-8")
+        assert.Equal(t, header.Get("Content-Disposition"), `attachment; filename="snippet-1-snippet-1.diff"`)
+    })
+}
+
+func TestSnippetTrash(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    csrfToken := ts.login(t)
+
+    code, _, body := ts.get(t, "/account/trash")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "Over the wintry forest")
+
+    tests := []struct {
+        name           string
+        urlPath        string
+        expectCode     int
+        expectLocation string
+    }{
+        {
+            name:           "Delete",
+            urlPath:        "/snippet/delete/1",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/account/trash",
+        },
+        {
+            name:       "Delete non-existent ID",
+            urlPath:    "/snippet/delete/2",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:           "Restore",
+            urlPath:        "/snippet/restore/3",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/snippet/view/3",
+        },
+        {
+            name:       "Restore snippet not in trash",
+            urlPath:    "/snippet/restore/1",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:           "Purge",
+            urlPath:        "/snippet/purge/3",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/account/trash",
+        },
+        {
+            name:       "Purge snippet not in trash",
+            urlPath:    "/snippet/purge/1",
+            expectCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("csrf_token", csrfToken)
+
+            code, header, _ := ts.postForm(t, tc.urlPath, form)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectLocation != "" {
+                assert.Equal(t, header.Get("Location"), tc.expectLocation)
+            }
+        })
+    }
+}
+
+func TestSnippetVisibility(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name          string
+        urlPath       string
+        authenticated bool
+        expectCode    int
+        expectBody    string
+    }{
+        {
+            name:       "Private snippet as anonymous user",
+            urlPath:    "/snippet/view/4",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Unlisted snippet by ID as anonymous user",
+            urlPath:    "/snippet/view/5",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Private snippet by slug as anonymous user",
+            urlPath:    "/s/Zr4-uW1cVbE",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Unlisted snippet by slug as anonymous user",
+            urlPath:    "/s/Xq3_aZ8rT0w",
+            expectCode: http.StatusOK,
+            expectBody: "First autumn morning...",
+        },
+        {
+            name:          "Private snippet as owner",
+            urlPath:       "/s/Zr4-uW1cVbE",
+            authenticated: true,
+            expectCode:    http.StatusOK,
+            expectBody:    "For my eyes only",
+        },
+        {
+            name:          "Private snippet by ID as owner",
+            urlPath:       "/snippet/view/4",
+            authenticated: true,
+            expectCode:    http.StatusMovedPermanently,
+        },
+        {
+            name:          "Unlisted snippet by ID as owner",
+            urlPath:       "/snippet/view/5",
+            authenticated: true,
+            expectCode:    http.StatusMovedPermanently,
+        },
+        {
+            name:          "Unlisted snippet as owner",
+            urlPath:       "/s/Xq3_aZ8rT0w",
+            authenticated: true,
+            expectCode:    http.StatusOK,
+            expectBody:    "Share it with this link",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            if tc.authenticated {
+                ts.login(t)
+            }
+
+            code, _, body := ts.get(t, tc.urlPath)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+}
+
+func TestSnippetCreate(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    csrfToken := ts.login(t)
+
+    t.Run("Language options", func(t *testing.T) {
+        _, _, body := ts.get(t, "/snippet/create")
+
+        for _, l := range highlight.Languages {
+            assert.StringContains(t, body, fmt.Sprintf(`<option value="%s" >%s</option>`, l.Name, l.Label))
+        }
+    })
+
+    tests := []struct {
+        name           string
+        title          string
+        content        string
+        visibility     string
+        language       string
+        format         string
+        expires        string
+        expiresAt      string
+        tags           string
+        expectCode     int
+        expectLocation string
+    }{
+        {
+            name:           "Valid submission",
+            title:          "O snail",
+            content:        "O snail\nClimb Mount Fuji,\nBut slowly, slowly!",
+            visibility:     "unlisted",
+            expires:        "1w",
+            format:         "plain",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:       "Empty title",
+            title:      "",
+            content:    "O snail",
+            visibility: "public",
+            expires:    "1w",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:       "Invalid visibility",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "secret",
+            expires:    "1w",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:           "Never expires",
+            title:          "O snail",
+            content:        "O snail",
+            visibility:     "public",
+            expires:        "never",
+            format:         "plain",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:           "Custom expiry",
+            title:          "O snail",
+            content:        "O snail",
+            visibility:     "public",
+            expires:        "custom",
+            expiresAt:      time.Now().UTC().AddDate(0, 0, 3).Format(customExpiryLayout),
+            format:         "plain",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:       "Custom expiry in the past",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "public",
+            expires:    "custom",
+            expiresAt:  "2001-01-01T00:00",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:           "Markdown",
+            title:          "Notes",
+            content:        "# Notes\n\n* one\n* two",
+            visibility:     "public",
+            format:         "markdown",
+            expires:        "1w",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:       "Invalid format",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "public",
+            format:     "html",
+            expires:    "1w",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:       "Invalid language",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "public",
+            language:   "klingon",
+            expires:    "1w",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:           "Tags",
+            title:          "O snail",
+            content:        "O snail",
+            visibility:     "public",
+            format:         "plain",
+            expires:        "1w",
+            tags:           " Haiku, poetry,,haiku ",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:       "Too many tags",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "public",
+            format:     "plain",
+            expires:    "1w",
+            tags:       "a, b, c, d, e, f",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:       "Invalid tag",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "public",
+            format:     "plain",
+            expires:    "1w",
+            tags:       "haiku, <script>",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:       "Invalid expiry",
+            title:      "O snail",
+            content:    "O snail",
+            visibility: "public",
+            expires:    "365",
+            expectCode: http.StatusUnprocessableEntity,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("title", tc.title)
+            form.Add("content", tc.content)
+            form.Add("visibility", tc.visibility)
+            form.Add("language", tc.language)
+            form.Add("format", tc.format)
+            form.Add("expires", tc.expires)
+            form.Add("expiresAt", tc.expiresAt)
+            form.Add("tags", tc.tags)
+            form.Add("csrf_token", csrfToken)
+
+            code, header, _ := ts.postForm(t, "/snippet/create", form)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectLocation != "" {
+                assert.Equal(t, header.Get("Location"), tc.expectLocation)
+            }
+        })
+    }
+}
+
+func TestSnippetBurnAfterReading(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    // Viewing the snippet only shows the reveal button, not the content.
+    code, _, body := ts.get(t, "/s/Bn6rE9wQx4M")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, `<form action="/s/Bn6rE9wQx4M/reveal" method="POST">`)
+
+    if strings.Contains(body, "correct horse battery staple") {
+        t.Error("content of burn after reading snippet sent before it was revealed")
+    }
+
+    csrfToken := extractCSRFToken(t, body)
+
+    tests := []struct {
+        name       string
+        urlPath    string
+        csrfToken  string
+        expectCode int
+        expectBody string
+    }{
+        {
+            name:       "Reveal",
+            urlPath:    "/s/Bn6rE9wQx4M/reveal",
+            csrfToken:  csrfToken,
+            expectCode: http.StatusOK,
+            expectBody: "correct horse battery staple",
+        },
+        {
+            name:       "Invalid CSRF token",
+            urlPath:    "/s/Bn6rE9wQx4M/reveal",
+            csrfToken:  "wrongToken",
+            expectCode: http.StatusBadRequest,
+        },
+        {
+            name:       "Non-existent slug",
+            urlPath:    "/s/AAAAAAAAAAA/reveal",
+            csrfToken:  csrfToken,
+            expectCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("csrf_token", tc.csrfToken)
+
+            code, _, body := ts.postForm(t, tc.urlPath, form)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+
+    t.Run("Past revision", func(t *testing.T) {
+        code, _, _ := ts.get(t, "/s/Bn6rE9wQx4M/revision/1")
+
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+}
+
+func TestSnippetPassphrase(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    // Viewing the snippet only shows the unlock form, not the content.
+    code, _, body := ts.get(t, "/s/Pw5tG8yHj3K")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, `<form action="/s/Pw5tG8yHj3K/unlock" method="POST" novalidate>`)
+
+    if strings.Contains(body, "Behind the locked door") {
+        t.Error("content of protected snippet sent before it was unlocked")
+    }
+
+    csrfToken := extractCSRFToken(t, body)
+
+    unlock := func(t *testing.T, passphrase string) (int, http.Header, string) {
+        form := url.Values{}
+        form.Add("passphrase", passphrase)
+        form.Add("csrf_token", csrfToken)
+
+        return ts.postForm(t, "/s/Pw5tG8yHj3K/unlock", form)
+    }
+
+    t.Run("Empty passphrase", func(t *testing.T) {
+        code, _, body := unlock(t, "")
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "This field cannot be empty.")
+    })
+
+    t.Run("Wrong passphrase", func(t *testing.T) {
+        code, _, body := unlock(t, "open barley")
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "Passphrase is incorrect.")
+    })
+
+    t.Run("Not echoed by the create form", func(t *testing.T) {
+        // Log in with another client, so that the client above stays logged out.
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        csrfToken := other.login(t)
+
+        form := url.Values{}
+        form.Add("title", "")
+        form.Add("content", "Behind the locked door")
+        form.Add("visibility", "public")
+        form.Add("expires", "1w")
+        form.Add("passphrase", "open sesame")
+        form.Add("csrf_token", csrfToken)
+
+        code, _, body := other.postForm(t, "/snippet/create", form)
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "Please enter the passphrase again.")
+
+        if strings.Contains(body, "open sesame") {
+            t.Error("passphrase sent back in the create form")
+        }
+    })
+
+    t.Run("Locked revision", func(t *testing.T) {
+        code, header, _ := ts.get(t, "/s/Pw5tG8yHj3K/revision/1")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/s/Pw5tG8yHj3K")
+    })
+
+    t.Run("Correct passphrase", func(t *testing.T) {
+        code, header, _ := unlock(t, "open sesame")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/s/Pw5tG8yHj3K")
+
+        code, _, body := ts.get(t, "/s/Pw5tG8yHj3K")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, "Behind the locked door")
+    })
+
+    t.Run("Rate limited", func(t *testing.T) {
+        // Failures are counted for the snippet, so a new session doesn't reset them.
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        _, _, body := other.get(t, "/s/Pw5tG8yHj3K")
+        csrfToken := extractCSRFToken(t, body)
+
+        form := url.Values{}
+        form.Add("passphrase", "open barley")
+        form.Add("csrf_token", csrfToken)
+
+        // One wrong passphrase has already been counted above.
+        for i := 0; i < 4; i++ {
+            code, _, _ := other.postForm(t, "/s/Pw5tG8yHj3K/unlock", form)
+            assert.Equal(t, code, http.StatusUnprocessableEntity)
+        }
+
+        form.Set("passphrase", "open sesame")
+
+        code, _, body := other.postForm(t, "/s/Pw5tG8yHj3K/unlock", form)
+
+        assert.Equal(t, code, http.StatusTooManyRequests)
+        assert.StringContains(t, body, "Too many wrong passphrases.")
+    })
+}
+
+func TestSnippetExpiryTime(t *testing.T) {
+    now := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name      string
+        expires   string
+        expiresAt string
+        want      time.Time
+        wantValid bool
+    }{
+        {
+            name:      "10 minutes",
+            expires:   "10m",
+            want:      time.Date(2024, 1, 31, 10, 10, 0, 0, time.UTC),
+            wantValid: true,
+        },
+        {
+            name:      "One month",
+            expires:   "1M",
+            want:      time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+            wantValid: true,
+        },
+        {
+            name:      "Never",
+            expires:   "never",
+            want:      time.Time{},
+            wantValid: true,
+        },
+        {
+            name:      "Custom",
+            expires:   "custom",
+            expiresAt: "2024-02-14T18:30",
+            want:      time.Date(2024, 2, 14, 18, 30, 0, 0, time.UTC),
+            wantValid: true,
+        },
+        {
+            name:      "Custom malformed",
+            expires:   "custom",
+            expiresAt: "14/02/2024",
+            want:      time.Time{},
+        },
+        {
+            name:    "Unknown choice",
+            expires: "1000y",
+            want:    time.Time{},
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := snippetCreateForm{Expires: tc.expires, ExpiresAt: tc.expiresAt}
+
+            assert.Equal(t, form.expiryTime(now), tc.want)
+            assert.Equal(t, form.Valid(), tc.wantValid)
+        })
+    }
+}
+
+func TestSnippetMarkdown(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, _, body := ts.get(t, "/s/Md8kT2vRw5N")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "<h1>Release notes</h1>")
+    assert.StringContains(t, body, "<strong>Fixed</strong> a bug &lt;script&gt;alert(1)&lt;/script&gt; reported here).")
+
+    for _, bad := range []string{"<script>alert", "javascript:"} {
+        if strings.Contains(body, bad) {
+            t.Errorf("rendered snippet contains %q", bad)
+        }
+    }
+}
+
+func TestSnippetRaw(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name              string
+        urlPath           string
+        expectCode        int
+        expectBody        string
+        expectDisposition string
+    }{
+        {
+            name:       "Raw by ID",
+            urlPath:    "/snippet/raw/1",
+            expectCode: http.StatusOK,
+            expectBody: "An old silent pond...",
+        },
+        {
+            name:       "Raw by slug",
+            urlPath:    "/s/Xq3_aZ8rT0w/raw",
+            expectCode: http.StatusOK,
+            expectBody: "First autumn morning...",
+        },
+        {
+            name:              "Download",
+            urlPath:           "/snippet/download/1",
+            expectCode:        http.StatusOK,
+            expectBody:        "An old silent pond...",
+            expectDisposition: "attachment; filename=an-old-silent-pond.txt",
+        },
+        {
+            name:              "Download Markdown",
+            urlPath:           "/s/Md8kT2vRw5N/download",
+            expectCode:        http.StatusOK,
+            expectDisposition: "attachment; filename=release-notes.md",
+        },
+        {
+            name:       "Unlisted by ID",
+            urlPath:    "/snippet/raw/5",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Private",
+            urlPath:    "/snippet/raw/4",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Burn after reading",
+            urlPath:    "/s/Bn6rE9wQx4M/raw",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Locked",
+            urlPath:    "/s/Pw5tG8yHj3K/download",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Non-existent ID",
+            urlPath:    "/snippet/raw/2",
+            expectCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, header, body := ts.get(t, tc.urlPath)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectCode != http.StatusOK {
+                return
+            }
+
+            assert.Equal(t, header.Get("Content-Type"), "text/plain; charset=utf-8")
+            assert.Equal(t, header.Get("Content-Disposition"), tc.expectDisposition)
+
+            if header.Get("ETag") == "" || header.Get("Content-Length") == "" {
+                t.Errorf("missing validator or length headers: %v", header)
+            }
+            assert.Equal(t, header.Get("Last-Modified"), "")
+
+            if tc.expectBody != "" {
+                assert.Equal(t, body, tc.expectBody)
+            }
+        })
+    }
+
+    t.Run("Not modified", func(t *testing.T) {
+        _, header, _ := ts.get(t, "/snippet/raw/1")
+
+        req, err := http.NewRequest(http.MethodGet, ts.URL+"/snippet/raw/1", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("If-None-Match", header.Get("ETag"))
+
+        res, err := ts.Client().Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer res.Body.Close()
+
+        assert.Equal(t, res.StatusCode, http.StatusNotModified)
+    })
+
+    // The creation time of a snippet says nothing about its current revision.
+    t.Run("If-Modified-Since", func(t *testing.T) {
+        req, err := http.NewRequest(http.MethodGet, ts.URL+"/snippet/raw/1", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+
+        res, err := ts.Client().Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer res.Body.Close()
+
+        assert.Equal(t, res.StatusCode, http.StatusOK)
+    })
+}
+
+func TestSnippetFiles(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    csrfToken := ts.login(t)
+
+    newForm := func(action string, files ...[2]string) url.Values {
+        form := url.Values{}
+        form.Add("title", "O snail")
+        form.Add("content", "O snail")
+        form.Add("visibility", "public")
+        form.Add("format", "plain")
+        form.Add("expires", "1w")
+        form.Add("action", action)
+        form.Add("csrf_token", csrfToken)
+
+        for i, f := range files {
+            form.Add(fmt.Sprintf("files[%d].name", i), f[0])
+            form.Add(fmt.Sprintf("files[%d].content", i), f[1])
+        }
+
+        return form
+    }
+
+    t.Run("Add file", func(t *testing.T) {
+        code, _, body := ts.postForm(t, "/snippet/create", newForm("addFile", [2]string{"a.go", "package a"}))
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, `<input type="text" name="files[0].name" value="a.go">`)
+        assert.StringContains(t, body, `<input type="text" name="files[1].name" value="">`)
+    })
+
+    t.Run("Remove file", func(t *testing.T) {
+        form := newForm("removeFile:0", [2]string{"a.go", "package a"}, [2]string{"b.go", "package b"})
+
+        code, _, body := ts.postForm(t, "/snippet/create", form)
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, `<input type="text" name="files[0].name" value="b.go">`)
+
+        if strings.Contains(body, "a.go") {
+            t.Error("removed file is still in the form")
+        }
+    })
+
+    tests := []struct {
+        name       string
+        files      [][2]string
+        expectCode int
+        expectBody string
+    }{
+        {
+            name:       "Valid files",
+            files:      [][2]string{{"main.go", "package main"}, {"run.sh", "go run ."}},
+            expectCode: http.StatusSeeOther,
+        },
+        {
+            name:       "Duplicate names",
+            files:      [][2]string{{"main.go", "package main"}, {"main.go", "package main"}},
+            expectCode: http.StatusUnprocessableEntity,
+            expectBody: "Another file has the same name.",
+        },
+        {
+            name:       "Path in name",
+            files:      [][2]string{{"../main.go", "package main"}},
+            expectCode: http.StatusUnprocessableEntity,
+            expectBody: "This field can only contain letters, digits, spaces, dots, dashes and underscores.",
+        },
+        {
+            name:       "Empty content",
+            files:      [][2]string{{"main.go", ""}},
+            expectCode: http.StatusUnprocessableEntity,
+            expectBody: "This field cannot be empty.",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, _, body := ts.postForm(t, "/snippet/create", newForm("publish", tc.files...))
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+
+    t.Run("View", func(t *testing.T) {
+        code, _, body := ts.get(t, "/s/pQ7nF2kLm0A")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, "<strong>splash.sh</strong>")
+        assert.StringContains(t, body, `<a href="/s/pQ7nF2kLm0A/file/2/raw">Raw</a>`)
+        assert.StringContains(t, body, `<span class="line" id="F2-L1">`)
+    })
+
+    t.Run("Raw file", func(t *testing.T) {
+        code, _, body := ts.get(t, "/s/pQ7nF2kLm0A/file/2/raw")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.Equal(t, body, "echo 'splash! Silence again.'")
+
+        code, _, _ = ts.get(t, "/s/pQ7nF2kLm0A/file/3/raw")
+
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+
+    t.Run("Zip", func(t *testing.T) {
+        res, err := ts.Client().Get(ts.URL + "/s/pQ7nF2kLm0A/zip")
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer res.Body.Close()
+
+        body, err := io.ReadAll(res.Body)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        assert.Equal(t, res.StatusCode, http.StatusOK)
+        assert.Equal(t, res.Header.Get("Content-Type"), "application/zip")
+        assert.Equal(t, res.Header.Get("Content-Disposition"), "attachment; filename=an-old-silent-pond.zip")
+
+        zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        var names []string
+        for _, f := range zr.File {
+            names = append(names, f.Name)
+        }
+
+        assert.Equal(t, strings.Join(names, ","), "an-old-silent-pond.txt,frog.txt,splash.sh")
+    })
+}
+
+func TestSearch(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name       string
+        urlPath    string
+        expectCode int
+        expectBody string
+    }{
+        {
+            name:       "Match",
+            urlPath:    "/search?q=pond",
+            expectCode: http.StatusOK,
+            expectBody: `<a href="/s/pQ7nF2kLm0A">An old silent <mark>pond</mark></a>`,
+        },
+        {
+            name:       "No match",
+            urlPath:    "/search?q=frog",
+            expectCode: http.StatusOK,
+            expectBody: "No snippets match your search.",
+        },
+        {
+            name:       "Second page",
+            urlPath:    "/search?q=pond&page=2",
+            expectCode: http.StatusOK,
+            expectBody: `<a href="/search?page=1&amp;q=pond">Previous</a>`,
+        },
+        {
+            name:       "Invalid page",
+            urlPath:    "/search?q=pond&page=0",
+            expectCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tc.urlPath)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+}
+
+func TestArchive(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name       string
+        urlPath    string
+        expectCode int
+        expectBody string
+    }{
+        {
+            name:       "First page",
+            urlPath:    "/snippets",
+            expectCode: http.StatusOK,
+            expectBody: `<a href="/s/pQ7nF2kLm0A">An old silent pond</a>`,
+        },
+        {
+            name:       "Older page",
+            urlPath:    "/snippets?after=1700000000-1",
+            expectCode: http.StatusOK,
+            expectBody: "There's nothing to see here yet!",
+        },
+        {
+            name:       "Invalid cursor",
+            urlPath:    "/snippets?after=1700000000",
+            expectCode: http.StatusBadRequest,
+        },
+        {
+            name:       "Both directions",
+            urlPath:    "/snippets?after=1700000000-1&before=1700000000-1",
+            expectCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tc.urlPath)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+}
+
+func TestTagView(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name       string
+        urlPath    string
+        expectCode int
+        expectBody string
+    }{
+        {
+            name:       "Tag",
+            urlPath:    "/tag/poetry",
+            expectCode: http.StatusOK,
+            expectBody: `<a href="/s/pQ7nF2kLm0A">An old silent pond</a>`,
+        },
+        {
+            name:       "Unused tag",
+            urlPath:    "/tag/k8s",
+            expectCode: http.StatusOK,
+            expectBody: "There's nothing to see here yet!",
+        },
+        {
+            name:       "Invalid tag",
+            urlPath:    "/tag/Poetry",
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Invalid cursor",
+            urlPath:    "/tag/poetry?before=x",
+            expectCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tc.urlPath)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectBody != "" {
+                assert.StringContains(t, body, tc.expectBody)
+            }
+        })
+    }
+}
+
+func TestSnippetFork(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, _, body := ts.get(t, "/s/pQ7nF2kLm0A")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "1 fork")
+
+    code, _, body = ts.get(t, "/s/Md8kT2vRw5N")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, `Forked from <a href="/s/pQ7nF2kLm0A">#1</a>`)
+    assert.StringContains(t, body, "0 forks")
+
+    // The unlisted original of a fork is only linked for its owner.
+    code, _, body = ts.get(t, "/s/Fk2uL7nPq1Z")
+
+    assert.Equal(t, code, http.StatusOK)
+
+    if strings.Contains(body, "Forked from") || strings.Contains(body, "Xq3_aZ8rT0w") {
+        t.Error("unlisted original of a fork disclosed")
+    }
+
+    csrfToken := ts.login(t)
+
+    _, _, body = ts.get(t, "/s/Fk2uL7nPq1Z")
+
+    assert.StringContains(t, body, `Forked from <a href="/s/Xq3_aZ8rT0w">#5</a>`)
+
+    tests := []struct {
+        name           string
+        urlPath        string
+        expectCode     int
+        expectLocation string
+    }{
+        {
+            name:           "Fork",
+            urlPath:        "/s/pQ7nF2kLm0A/fork",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:           "Fork own private snippet",
+            urlPath:        "/s/Zr4-uW1cVbE/fork",
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/bT9vQ2xLd0E",
+        },
+        {
+            name:       "Non-existent slug",
+            urlPath:    "/s/aaaaaaaaaaa/fork",
+            expectCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("csrf_token", csrfToken)
+
+            code, header, _ := ts.postForm(t, tc.urlPath, form)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectLocation != "" {
+                assert.Equal(t, header.Get("Location"), tc.expectLocation)
+            }
+        })
+    }
+
+    t.Run("Unlisted snippet of another user", func(t *testing.T) {
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        csrfToken := other.loginAs(t, "carol@example.com", "123456")
+
+        code, _, body := other.get(t, "/s/Xq3_aZ8rT0w")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, `<form class="button" action="/s/Xq3_aZ8rT0w/fork" method="POST">`)
+
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := other.postForm(t, "/s/Xq3_aZ8rT0w/fork", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/s/bT9vQ2xLd0E")
+    })
+
+    t.Run("Private snippet of another user", func(t *testing.T) {
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        csrfToken := other.loginAs(t, "carol@example.com", "123456")
+
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, _, _ := other.postForm(t, "/s/Zr4-uW1cVbE/fork", form)
+
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+}
+
+func TestSnippetStar(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, _, body := ts.get(t, "/s/pQ7nF2kLm0A")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "2 stars")
+
+    code, _, body = ts.get(t, "/")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "Most Starred This Week")
+
+    csrfToken := ts.login(t)
+
+    code, _, body = ts.get(t, "/s/pQ7nF2kLm0A")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "<button>Unstar</button>")
+
+    tests := []struct {
+        name           string
+        urlPath        string
+        csrfToken      string
+        expectCode     int
+        expectLocation string
+    }{
+        {
+            name:           "Star",
+            urlPath:        "/s/pQ7nF2kLm0A/star",
+            csrfToken:      csrfToken,
+            expectCode:     http.StatusSeeOther,
+            expectLocation: "/s/pQ7nF2kLm0A",
+        },
+        {
+            name:       "Non-existent slug",
+            urlPath:    "/s/aaaaaaaaaaa/star",
+            csrfToken:  csrfToken,
+            expectCode: http.StatusNotFound,
+        },
+        {
+            name:       "Missing CSRF token",
+            urlPath:    "/s/pQ7nF2kLm0A/star",
+            expectCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("csrf_token", tc.csrfToken)
+
+            code, header, _ := ts.postForm(t, tc.urlPath, form)
+
+            assert.Equal(t, code, tc.expectCode)
+
+            if tc.expectLocation != "" {
+                assert.Equal(t, header.Get("Location"), tc.expectLocation)
+            }
+        })
+    }
+
+    t.Run("Unlisted snippet of another user", func(t *testing.T) {
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        csrfToken := other.loginAs(t, "carol@example.com", "123456")
+
+        code, _, body := other.get(t, "/s/Xq3_aZ8rT0w")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, `<form class="button" action="/s/Xq3_aZ8rT0w/star" method="POST">`)
+
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := other.postForm(t, "/s/Xq3_aZ8rT0w/star", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/s/Xq3_aZ8rT0w")
+    })
+
+    t.Run("Private snippet of another user", func(t *testing.T) {
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        csrfToken := other.loginAs(t, "carol@example.com", "123456")
+
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, _, _ := other.postForm(t, "/s/Zr4-uW1cVbE/star", form)
+
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+}
+
+func TestSnippetComments(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, _, body := ts.get(t, "/s/pQ7nF2kLm0A")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, `<div class="comment depth-1" id="C2">`)
+    assert.StringContains(t, body, `on <a href="#L1">line 1</a>`)
+    assert.StringContains(t, body, `<a href="/user/login">Log in</a> to comment.`)
+
+    csrfToken := ts.login(t)
+
+    t.Run("Add", func(t *testing.T) {
+        tests := []struct {
+            name           string
+            content        string
+            line           string
+            parentID       string
+            expectCode     int
+            expectLocation string
+        }{
+            {
+                name:           "Comment",
+                content:        "Nice.",
+                expectCode:     http.StatusSeeOther,
+                expectLocation: "/s/pQ7nF2kLm0A#C4",
+            },
+            {
+                name:           "Reply on a line",
+                content:        "Indeed.",
+                line:           "1",
+                parentID:       "1",
+                expectCode:     http.StatusSeeOther,
+                expectLocation: "/s/pQ7nF2kLm0A#C4",
+            },
+            {
+                name:       "Empty content",
+                content:    " ",
+                expectCode: http.StatusUnprocessableEntity,
+            },
+            {
+                name:       "Line out of range",
+                content:    "Nice.",
+                line:       "2",
+                expectCode: http.StatusUnprocessableEntity,
+            },
+            {
+                name:       "Non-existent parent",
+                content:    "Nice.",
+                parentID:   "99",
+                expectCode: http.StatusBadRequest,
+            },
+        }
+
+        for _, tc := range tests {
+            t.Run(tc.name, func(t *testing.T) {
+                form := url.Values{}
+                form.Add("content", tc.content)
+                form.Add("line", tc.line)
+                form.Add("parentID", tc.parentID)
+                form.Add("csrf_token", csrfToken)
+
+                code, header, _ := ts.postForm(t, "/s/pQ7nF2kLm0A/comment", form)
+
+                assert.Equal(t, code, tc.expectCode)
+
+                if tc.expectLocation != "" {
+                    assert.Equal(t, header.Get("Location"), tc.expectLocation)
+                }
+            })
+        }
+    })
+
+    t.Run("Delete", func(t *testing.T) {
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := ts.postForm(t, "/comment/delete/1", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/s/pQ7nF2kLm0A#comments")
+
+        code, _, _ = ts.postForm(t, "/comment/delete/99", form)
+
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+
+    t.Run("Unlisted snippet of another user", func(t *testing.T) {
+        other := newTestServer(t, app.routes())
+        defer other.Close()
+
+        csrfToken := other.loginAs(t, "carol@example.com", "123456")
+
+        code, _, body := other.get(t, "/s/Xq3_aZ8rT0w")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, `<form action="/s/Xq3_aZ8rT0w/comment" method="POST" novalidate>`)
+
+        form := url.Values{}
+        form.Add("content", "Nice.")
+        form.Add("csrf_token", csrfToken)
+
+        code, header, _ := other.postForm(t, "/s/Xq3_aZ8rT0w/comment", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, header.Get("Location"), "/s/Xq3_aZ8rT0w#C4")
+    })
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"snippetbox/internal/highlight"
+	"snippetbox/internal/models"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-playground/form/v4"
 )
@@ -61,6 +67,60 @@ func (app *application) isAuthenticated(r *http.Request) bool {
     return isAuthenticated
 }
 
+// authenticatedUserID returns the ID of the user making the request, or 0 if the request is not
+// authenticated.
+func (app *application) authenticatedUserID(r *http.Request) int {
+    if !app.isAuthenticated(r) {
+        return 0
+    }
+
+    return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// destroyUserSessions destroys every session in which the user userID is logged in, except the
+// session with the token keep. Pass an empty keep to destroy them all. The sessions in the index of
+// table user_session are destroyed first. The session store is then swept for sessions which aren't
+// in the index, such as those created before it existed or whose index row couldn't be inserted.
+func (app *application) destroyUserSessions(ctx context.Context, userID int, keep string) error {
+    tokens, err := app.session.RevokeOthers(userID, keep)
+    if err != nil {
+        return err
+    }
+
+    for _, token := range tokens {
+        err = app.sessionManager.Store.Delete(token)
+        if err != nil {
+            return err
+        }
+    }
+
+    return app.sessionManager.Iterate(ctx, func(ctx context.Context) error {
+        if app.sessionManager.GetInt(ctx, "authenticatedUserID") != userID || app.sessionManager.Token(ctx) == keep {
+            return nil
+        }
+
+        return app.sessionManager.Destroy(ctx)
+    })
+}
+
+// background runs fn in a new goroutine, which the server waits for before it exits. A panic in
+// fn is logged rather than crashing the server.
+func (app *application) background(fn func()) {
+    app.wg.Add(1)
+
+    go func() {
+        defer app.wg.Done()
+
+        defer func() {
+            if err := recover(); err != nil {
+                app.logger.Error(fmt.Sprintf("%s", err))
+            }
+        }()
+
+        fn()
+    }()
+}
+
 func (app *application) decodePostForm(r *http.Request, varForm any) error {
     err := r.ParseForm()
     if err != nil {
@@ -83,3 +143,41 @@ func (app *application) decodePostForm(r *http.Request, varForm any) error {
 
     return nil
 }
+
+// snippetFilename returns the name of the file which snippet s is downloaded as. It is derived
+// from the title, with the extension of the format or language of the snippet.
+func snippetFilename(s models.Snippet) string {
+    var sb strings.Builder
+
+    separate := false
+    for _, r := range strings.ToLower(s.Title) {
+        if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+            separate = true
+            continue
+        }
+
+        if utf8.RuneCountInString(sb.String()) >= 64 {
+            break
+        }
+
+        if separate && sb.Len() > 0 {
+            sb.WriteByte('-')
+        }
+        sb.WriteRune(r)
+        separate = false
+    }
+
+    name := sb.String()
+    if name == "" {
+        name = fmt.Sprintf("snippet-%d", s.ID)
+    }
+
+    ext := ".txt"
+    if s.Format == models.FormatMarkdown {
+        ext = ".md"
+    } else if l := highlight.Lookup(s.Language); l != nil {
+        ext = l.Extension
+    }
+
+    return name + ext
+}
@@ -1,17 +1,82 @@
 package main
 
-import "snippetbox/internal/models"
+import (
+	"snippetbox/internal/models"
+	"time"
+)
 
 type userModelInterface interface {
-    Insert(name, email, password string) error
+    Insert(name, email, password string) (int, error)
     Get(id int) (models.User, error)
+    GetByEmail(email string) (models.User, error)
     Exists(id int) (bool, error)
     Authenticate(email, password string) (int, error)
+    Verify(id int, email string) error
     UpdatePassword(id int, currentPassword, newPassword string) error
+    EnableTOTP(id int, secret string) ([]string, error)
+    DisableTOTP(id int) error
+    CheckTOTP(id int, code string, now time.Time) error
+    UseRecoveryCode(id int, code string) error
 }
 
 type snippetModelInterface interface {
-    Insert(title string, content string, expires int) (int, error)
+    Insert(s models.NewSnippet) (string, error)
     Get(id int) (models.Snippet, error)
+    GetBySlug(slug string) (models.Snippet, error)
     Latest(n int) ([]models.Snippet, error)
+    ByUser(userID int) ([]models.Snippet, error)
+    Archive(tag string, cursor models.Cursor, n int, newer bool) ([]models.Snippet, error)
+    Search(query string, userID, limit, offset int) ([]models.Snippet, error)
+    Update(id, userID int, title string, content string) error
+    Revisions(id int) ([]models.Revision, error)
+    Revision(id, n int) (models.Revision, error)
+    Delete(id, userID int) error
+    Restore(id, userID int) error
+    Purge(id, userID int) error
+    Trash(userID int) ([]models.Snippet, error)
+    DeleteExpired(batchSize int) (int, error)
+    Burn(id int) (models.Snippet, error)
+    Unlock(id int, passphrase string) error
+    Files(id int) ([]models.File, error)
+    File(id, n int) (models.File, error)
+    Star(id, userID int) error
+    Unstar(id, userID int) error
+    Stars(id, userID int) (int, bool, error)
+    Starred(userID int) ([]models.Snippet, error)
+    MostStarred(since time.Time, n int) ([]models.Snippet, error)
+    ForkCount(id int) (int, error)
+    Tags(id int) ([]string, error)
+    PopularTags(n int) ([]models.Tag, error)
+}
+
+type commentModelInterface interface {
+    Insert(snippetID, userID, parentID, line int, content string) (int, error)
+    Get(id int) (models.Comment, error)
+    BySnippet(id int) ([]models.Comment, error)
+    Delete(id, userID int) error
+}
+
+type passwordResetModelInterface interface {
+    Insert(userID int, ttl time.Duration) (string, error)
+    Check(token string) (int, error)
+    Reset(token, newPassword string) (int, error)
+    DeleteExpired(batchSize int) (int, error)
+}
+
+type loginFailureModelInterface interface {
+    BlockedUntil(subjects ...string) (time.Time, error)
+    Fail(subject string) (int, error)
+    Block(subject string, until time.Time) error
+    Reset(subject string) error
+    DeleteExpired(batchSize int) (int, error)
+}
+
+type sessionModelInterface interface {
+    DeleteExpired(batchSize int) (int, error)
+    Insert(token string, userID int, userAgent, ip string) error
+    Touch(token string) error
+    ByUser(userID int) ([]models.Session, error)
+    Revoke(id, userID int) (string, error)
+    RevokeOthers(userID int, keep string) ([]string, error)
+    DeleteOrphans(batchSize int) (int, error)
 }
\ No newline at end of file
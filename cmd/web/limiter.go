@@ -0,0 +1,61 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// failureLimiter limits the number of failed attempts which can be made against a key, such as a
+// snippet ID, within a sliding window of time. It is safe for concurrent use.
+type failureLimiter struct {
+    mu       sync.Mutex
+    max      int
+    window   time.Duration
+    failures map[int][]time.Time
+}
+
+func newFailureLimiter(max int, window time.Duration) *failureLimiter {
+    return &failureLimiter{
+        max:      max,
+        window:   window,
+        failures: make(map[int][]time.Time),
+    }
+}
+
+// Allow reports whether another attempt may be made against key.
+func (l *failureLimiter) Allow(key int) bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    return len(l.prune(key, time.Now())) < l.max
+}
+
+// Fail records a failed attempt against key.
+func (l *failureLimiter) Fail(key int) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    l.failures[key] = append(l.prune(key, now), now)
+}
+
+// prune drops the failures of key which are older than the window and returns the rest. The
+// caller must hold l.mu.
+func (l *failureLimiter) prune(key int, now time.Time) []time.Time {
+    failures := l.failures[key]
+
+    i := 0
+    for i < len(failures) && now.Sub(failures[i]) >= l.window {
+        i++
+    }
+    failures = failures[i:]
+
+    if len(failures) == 0 {
+        delete(l.failures, key)
+        return nil
+    }
+
+    l.failures[key] = failures
+
+    return failures
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"flag"
@@ -11,7 +12,10 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"snippetbox/internal/mailer"
 	"snippetbox/internal/models"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/alexedwards/scs/mysqlstore"
@@ -19,12 +23,22 @@ import (
 )
 
 type application struct {
-    debug          bool
-    logger         *slog.Logger
-    templateCache  map[string]*template.Template
-    sessionManager *scs.SessionManager
-    user           userModelInterface
-    snippet        snippetModelInterface
+    debug            bool
+    logger           *slog.Logger
+    templateCache    map[string]*template.Template
+    sessionManager   *scs.SessionManager
+    user             userModelInterface
+    snippet          snippetModelInterface
+    comment          commentModelInterface
+    passwordReset    passwordResetModelInterface
+    loginFailure     loginFailureModelInterface
+    session          sessionModelInterface
+    unlockLimiter    *failureLimiter
+    twoFactorLimiter *failureLimiter
+    mailer           mailer.Mailer
+    tokens           *tokenSigner // Signs the links sent in emails.
+    baseURL          string // The URL at which users reach the application, used in links in emails.
+    wg               sync.WaitGroup // Tracks the goroutines started by app.background.
 }
 
 func main() {
@@ -32,10 +46,34 @@ func main() {
     dbDriver := flag.String("driver", "mysql", "Database driver name")
     dsn := flag.String("dsn", "zzh:zzhpwd@tcp(localhost:3306)/zsnippetbox?parseTime=true", "Data source name")
     debug := flag.Bool("debug", false, "Enable debug mode")
+    reapInterval := flag.Duration("reap-interval", 5*time.Minute, "Interval between removals of expired snippets and sessions (0 disables removal)")
+    reapBatchSize := flag.Int("reap-batch-size", 1000, "Maximum number of expired rows removed by a single statement")
+    baseURL := flag.String("base-url", "https://localhost:4000", "URL at which users reach the application, used in links in emails")
+    tokenKey := flag.String("token-key", "", "Secret key of at least 32 bytes for signing links in emails (a random key which changes on every start is used if empty)")
+    smtpHost := flag.String("smtp-host", "", "SMTP server host (emails are written to stdout if empty)")
+    smtpPort := flag.Int("smtp-port", 587, "SMTP server port")
+    smtpUsername := flag.String("smtp-username", "", "SMTP username")
+    smtpPassword := flag.String("smtp-password", "", "SMTP password")
+    smtpSender := flag.String("smtp-sender", "Snippetbox <no-reply@snippetbox.example.com>", "Sender of emails")
     flag.Parse()
 
     logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+    key := []byte(*tokenKey)
+    if len(key) == 0 {
+        key = make([]byte, 32)
+        rand.Read(key)
+        logger.Warn("no token key given, links in emails will stop working when the server restarts")
+    } else if len(key) < 32 {
+        logger.Error("the token key must be at least 32 bytes long")
+        os.Exit(1)
+    }
+
+    var m mailer.Mailer = mailer.NewWriter(os.Stdout, *smtpSender)
+    if *smtpHost != "" {
+        m = mailer.NewSMTP(*smtpHost, *smtpPort, *smtpUsername, *smtpPassword, *smtpSender)
+    }
+
     db, err := openDB(*dbDriver, *dsn)
     if err != nil {
         logger.Error(err.Error())
@@ -50,17 +88,30 @@ func main() {
     }
 
     sessionManager := scs.New()
-    sessionManager.Store = mysqlstore.New(db)
+    // The store's own cleanup goroutine is disabled because expired sessions are removed by
+    // app.reapExpired, which stops cleanly when the server shuts down.
+    sessionManager.Store = mysqlstore.NewWithCleanupInterval(db, 0)
     sessionManager.Lifetime = 12 * time.Hour
     sessionManager.Cookie.Secure = true // Setting this means the cookie will only be sent by a user's web browser when an HTTPS connection is used.
 
     app := &application{
-        debug:          *debug,
-        logger:         logger,
-        templateCache:  templateCache,
-        sessionManager: sessionManager,
-        user:           &models.UserModel{DB: db},
-        snippet:        &models.SnippetModel{DB: db},
+        debug:            *debug,
+        logger:           logger,
+        templateCache:    templateCache,
+        sessionManager:   sessionManager,
+        user:             &models.UserModel{DB: db},
+        snippet:          &models.SnippetModel{DB: db},
+        comment:          &models.CommentModel{DB: db},
+        passwordReset:    &models.PasswordResetModel{DB: db},
+        loginFailure:     &models.LoginFailureModel{DB: db},
+        session:          &models.SessionModel{DB: db},
+        // Allow 5 wrong passphrases for each protected snippet every 15 minutes.
+        unlockLimiter:    newFailureLimiter(5, 15*time.Minute),
+        // Allow 5 wrong one-time passwords or recovery codes for each user every 15 minutes.
+        twoFactorLimiter: newFailureLimiter(5, 15*time.Minute),
+        mailer:           m,
+        tokens:           newTokenSigner(key),
+        baseURL:          strings.TrimSuffix(*baseURL, "/"),
     }
 
     tlsConfig := &tls.Config{
@@ -77,6 +128,18 @@ func main() {
         WriteTimeout: 10 * time.Second,
     }
 
+    // Start the background worker which removes expired snippets and sessions. Cancelling
+    // reaperCtx stops it, and reaperDone is closed once it has returned.
+    reaperCtx, stopReaper := context.WithCancel(context.Background())
+    reaperDone := make(chan struct{})
+    go func() {
+        defer close(reaperDone)
+
+        if *reapInterval > 0 {
+            app.reapExpired(reaperCtx, *reapInterval, *reapBatchSize)
+        }
+    }()
+
     idleConnsClosed := make(chan struct{})
     go func() {
         sigint := make(chan os.Signal, 1)
@@ -89,6 +152,13 @@ func main() {
             logger.Error(fmt.Sprintf("HTTP server Shutdown: %v", err.Error()))
         }
 
+        // Stop the reaper and wait for any batch in progress to finish.
+        stopReaper()
+        <-reaperDone
+
+        // Wait for background tasks such as sending emails.
+        app.wg.Wait()
+
         close(idleConnsClosed)
     }()
 
@@ -110,6 +110,14 @@ func (app *application) authenticate(next http.Handler) http.Handler {
         if exists {
             ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
             r = r.WithContext(ctx)
+
+            // Record the activity, so that the user can see when each of their sessions was last
+            // used.
+            err = app.session.Touch(app.sessionManager.Token(r.Context()))
+            if err != nil {
+                app.serverError(w, r, err)
+                return
+            }
         }
 
         next.ServeHTTP(w, r)
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// reapExpired hard-deletes expired snippets, sessions, password reset tokens and login failures,
+// and the index rows of sessions which have ended, every interval until ctx is cancelled. Rows are
+// deleted in batches of batchSize so that a large backlog doesn't hold locks on the tables for long.
+func (app *application) reapExpired(ctx context.Context, interval time.Duration, batchSize int) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            app.reap(ctx, "snippet", app.snippet.DeleteExpired, batchSize)
+            app.reap(ctx, "sessions", app.session.DeleteExpired, batchSize)
+            app.reap(ctx, "password_reset_token", app.passwordReset.DeleteExpired, batchSize)
+            app.reap(ctx, "login_failure", app.loginFailure.DeleteExpired, batchSize)
+            app.reap(ctx, "user_session", app.session.DeleteOrphans, batchSize)
+        }
+    }
+}
+
+// reap calls deleteExpired repeatedly until a batch comes back short or ctx is cancelled, and
+// logs the total number of rows removed from table.
+func (app *application) reap(ctx context.Context, table string, deleteExpired func(int) (int, error), batchSize int) {
+    total := 0
+
+    for ctx.Err() == nil {
+        n, err := deleteExpired(batchSize)
+        if err != nil {
+            app.logger.Error(err.Error(), "table", table)
+            break
+        }
+
+        total += n
+
+        if n < batchSize {
+            break
+        }
+    }
+
+    if total > 0 {
+        app.logger.Info("removed expired rows", "table", table, "count", total)
+    }
+}
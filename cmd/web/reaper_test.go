@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestReap(t *testing.T) {
+    var buf bytes.Buffer
+
+    app := newTestApplication(t)
+    app.logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+    // Pretend there are 25 expired rows, which are removed at most batchSize at a time.
+    remaining := 25
+    calls := 0
+    deleteExpired := func(batchSize int) (int, error) {
+        calls++
+        n := min(remaining, batchSize)
+        remaining -= n
+        return n, nil
+    }
+
+    app.reap(context.Background(), "snippet", deleteExpired, 10)
+
+    assert.Equal(t, remaining, 0)
+    assert.Equal(t, calls, 3)
+    assert.StringContains(t, buf.String(), "count=25")
+}
+
+func TestReapExpiredStops(t *testing.T) {
+    app := newTestApplication(t)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+
+    go func() {
+        defer close(done)
+        app.reapExpired(ctx, time.Millisecond, 10)
+    }()
+
+    time.Sleep(5 * time.Millisecond)
+    cancel()
+
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatal("reapExpired did not stop after its context was cancelled")
+    }
+}
@@ -19,11 +19,34 @@ func (app *application) routes() http.Handler {
 
     mux.Handle("GET /{$}", dynamic.ThenFunc(app.home))
     mux.Handle("GET /about", dynamic.ThenFunc(app.about))
+    mux.Handle("GET /snippets", dynamic.ThenFunc(app.archive))
+    mux.Handle("GET /tag/{name}", dynamic.ThenFunc(app.tagView))
+    mux.Handle("GET /search", dynamic.ThenFunc(app.search))
     mux.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
     mux.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
     mux.Handle("GET /user/login", dynamic.ThenFunc(app.userLogin))
     mux.Handle("POST /user/login", dynamic.ThenFunc(app.userLoginPost))
-    mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetView))
+    mux.Handle("GET /user/login/2fa", dynamic.ThenFunc(app.userLoginTwoFactor))
+    mux.Handle("POST /user/login/2fa", dynamic.ThenFunc(app.userLoginTwoFactorPost))
+    mux.Handle("GET /user/verify", dynamic.ThenFunc(app.userVerify))
+    mux.Handle("GET /user/password/forgot", dynamic.ThenFunc(app.userPasswordForgot))
+    mux.Handle("POST /user/password/forgot", dynamic.ThenFunc(app.userPasswordForgotPost))
+    mux.Handle("GET /user/password/reset/{token}", dynamic.ThenFunc(app.userPasswordReset))
+    mux.Handle("POST /user/password/reset/{token}", dynamic.ThenFunc(app.userPasswordResetPost))
+    mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetRedirect))
+    mux.Handle("GET /snippet/view/{id}/revision/{n}", dynamic.ThenFunc(app.snippetRedirect))
+    mux.Handle("GET /s/{slug}", dynamic.ThenFunc(app.snippetView))
+    mux.Handle("GET /s/{slug}/revision/{n}", dynamic.ThenFunc(app.snippetRevisionView))
+    mux.Handle("POST /s/{slug}/reveal", dynamic.ThenFunc(app.snippetRevealPost))
+    mux.Handle("POST /s/{slug}/unlock", dynamic.ThenFunc(app.snippetUnlockPost))
+    mux.Handle("GET /s/{slug}/raw", dynamic.ThenFunc(app.snippetRaw))
+    mux.Handle("GET /s/{slug}/download", dynamic.ThenFunc(app.snippetDownload))
+    mux.Handle("GET /s/{slug}/file/{n}/raw", dynamic.ThenFunc(app.snippetFileRaw))
+    mux.Handle("GET /s/{slug}/zip", dynamic.ThenFunc(app.snippetZip))
+    mux.Handle("GET /snippet/raw/{id}", dynamic.ThenFunc(app.snippetRaw))
+    mux.Handle("GET /snippet/download/{id}", dynamic.ThenFunc(app.snippetDownload))
+    mux.Handle("GET /diff", dynamic.ThenFunc(app.diffView))
+    mux.Handle("GET /diff/download", dynamic.ThenFunc(app.diffDownload))
 
     // Protected (authenticated-only) routes using the "protected" middleware chain which includes 
     // the requireAuthentication middleware.
@@ -33,8 +56,23 @@ func (app *application) routes() http.Handler {
     mux.Handle("GET /account/view", protected.ThenFunc(app.accountView))
     mux.Handle("GET /account/password/update", protected.ThenFunc(app.accountPasswordUpdate))
     mux.Handle("POST /account/password/update", protected.ThenFunc(app.accountPasswordUpdatePost))
+    mux.Handle("GET /account/2fa", protected.ThenFunc(app.accountTwoFactor))
+    mux.Handle("POST /account/2fa/enable", protected.ThenFunc(app.accountTwoFactorEnablePost))
+    mux.Handle("POST /account/2fa/disable", protected.ThenFunc(app.accountTwoFactorDisablePost))
+    mux.Handle("POST /account/session/revoke/{id}", protected.ThenFunc(app.accountSessionRevokePost))
+    mux.Handle("POST /account/session/revoke-others", protected.ThenFunc(app.accountSessionRevokeOthersPost))
     mux.Handle("GET /snippet/create", protected.ThenFunc(app.snippetCreate))
     mux.Handle("POST /snippet/create", protected.ThenFunc(app.snippetCreatePost))
+    mux.Handle("GET /snippet/edit/{id}", protected.ThenFunc(app.snippetEdit))
+    mux.Handle("POST /snippet/edit/{id}", protected.ThenFunc(app.snippetEditPost))
+    mux.Handle("POST /s/{slug}/fork", protected.ThenFunc(app.snippetForkPost))
+    mux.Handle("POST /s/{slug}/star", protected.ThenFunc(app.snippetStarPost))
+    mux.Handle("POST /s/{slug}/comment", protected.ThenFunc(app.snippetCommentPost))
+    mux.Handle("POST /comment/delete/{id}", protected.ThenFunc(app.commentDeletePost))
+    mux.Handle("POST /snippet/delete/{id}", protected.ThenFunc(app.snippetDeletePost))
+    mux.Handle("POST /snippet/restore/{id}", protected.ThenFunc(app.snippetRestorePost))
+    mux.Handle("POST /snippet/purge/{id}", protected.ThenFunc(app.snippetPurgePost))
+    mux.Handle("GET /account/trash", protected.ThenFunc(app.accountTrash))
 
     standard := alice.New(app.recoverPanic, app.logRequest, commonHeaders)
 
@@ -5,22 +5,129 @@ import (
 	"io/fs"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"snippetbox/internal/diff"
+	"snippetbox/internal/highlight"
+	"snippetbox/internal/markdown"
 	"snippetbox/internal/models"
 	"snippetbox/ui"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/justinas/nosurf"
 )
 
 type templateData struct {
-    CurrentYear     int
-    IsAuthenticated bool
-    CSRFToken       string
-    Flash           string
-    Form            any
-    Snippet         models.Snippet
-    Snippets        []models.Snippet
-    User            models.User
+    CurrentYear         int
+    IsAuthenticated     bool
+    AuthenticatedUserID int
+    CSRFToken           string
+    Flash               string
+    Form                any
+    Snippet             models.Snippet
+    Snippets            []models.Snippet
+    Revision            models.Revision
+    Revisions           []models.Revision
+    Files               []models.File
+    Tags                []string  // The tags of Snippet.
+    Forks               int  // The number of forks of Snippet.
+    ForkedFrom          models.Snippet  // The snippet which Snippet is a fork of, if the user may see it.
+    Stars               int  // The number of stars of Snippet.
+    IsStarred           bool  // Whether the current user has starred Snippet.
+    Starred             []models.Snippet  // The snippets starred by the current user.
+    MostStarred         []models.Snippet
+    Comments            []threadedComment
+    Tag                 string  // The tag which the listed snippets have.
+    TagCloud            []cloudTag
+    Query               string  // The search query.
+    Pagination          Pagination
+    Hunks               []diff.Hunk
+    DiffView            string
+    User                models.User
+    TOTPSecret          string  // The secret offered to a user who is turning on two-factor authentication.
+    QRCode              template.HTML  // An SVG image of a QR code holding the provisioning URI of TOTPSecret.
+    RecoveryCodes       []string
+    Sessions            []models.Session  // The login sessions of the current user.
+    CurrentSessionID    int  // The ID of the session in Sessions which made the request.
+}
+
+// Pagination holds the URLs of the pages next to the current page of a listing. A URL is empty if
+// there is no such page. It is rendered by the "pagination" template.
+type Pagination struct {
+    Prev string
+    Next string
+}
+
+// cloudTag is a tag shown in a tag cloud. Its weight, from 1 to 5, decides how large it is shown.
+type cloudTag struct {
+    Name   string
+    Weight int
+}
+
+// newTagCloud weighs tags according to how many snippets have them, from 1 for the least used to
+// 5 for the most used.
+func newTagCloud(tags []models.Tag) []cloudTag {
+    if len(tags) == 0 {
+        return nil
+    }
+
+    least, most := tags[0].Count, tags[0].Count
+    for _, t := range tags {
+        least, most = min(least, t.Count), max(most, t.Count)
+    }
+
+    cloud := make([]cloudTag, len(tags))
+    for i, t := range tags {
+        cloud[i] = cloudTag{Name: t.Name, Weight: 1}
+        if most > least {
+            cloud[i].Weight = 1 + 4*(t.Count-least)/(most-least)
+        }
+    }
+
+    return cloud
+}
+
+// threadedComment is a comment shown in a thread at the given depth: 0 for a comment which
+// doesn't reply to another one, 1 for a reply to such a comment and so on.
+type threadedComment struct {
+    models.Comment
+    Depth int
+}
+
+// maxCommentDepth is the depth beyond which replies aren't indented any further.
+const maxCommentDepth = 4
+
+// threadComments orders comments so that each comment is followed by its replies, which are
+// ordered in the same way. Comments which reply to the same comment keep their order. Deleted
+// comments are left out, unless they have replies which are shown.
+func threadComments(comments []models.Comment) []threadedComment {
+    replies := make(map[int][]models.Comment)
+    for _, c := range comments {
+        replies[c.ParentID] = append(replies[c.ParentID], c)
+    }
+
+    var (
+        thread []threadedComment
+        walk   func(parentID, depth int)
+    )
+
+    walk = func(parentID, depth int) {
+        for _, c := range replies[parentID] {
+            i := len(thread)
+            thread = append(thread, threadedComment{Comment: c, Depth: min(depth, maxCommentDepth)})
+            walk(c.ID, depth+1)
+
+            if !c.Deleted.IsZero() && len(thread) == i+1 {
+                thread = thread[:i]
+            }
+        }
+    }
+    walk(0, 0)
+
+    return thread
 }
 
 func humanDate(t time.Time) string {
@@ -32,8 +139,135 @@ func humanDate(t time.Time) string {
     return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// searchTermsRX returns a regular expression which matches the words of the search query
+// regardless of case, or nil if the query has no words. Words shorter than three characters are
+// left out, because MySQL doesn't index them.
+func searchTermsRX(query string) *regexp.Regexp {
+    var terms []string
+
+    for _, word := range strings.FieldsFunc(query, func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    }) {
+        word = strings.ToLower(word)
+        if utf8.RuneCountInString(word) >= 3 && !slices.Contains(terms, word) {
+            terms = append(terms, regexp.QuoteMeta(word))
+        }
+    }
+
+    if len(terms) == 0 {
+        return nil
+    }
+
+    return regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// excerpt returns about n characters of text around the first word of query which it contains.
+func excerpt(text, query string, n int) string {
+    runes := []rune(text)
+    if len(runes) <= n {
+        return text
+    }
+
+    start := 0
+    if rx := searchTermsRX(query); rx != nil {
+        if loc := rx.FindStringIndex(text); loc != nil {
+            // Show some context before the match.
+            start = max(utf8.RuneCountInString(text[:loc[0]])-n/4, 0)
+        }
+    }
+    end := min(start+n, len(runes))
+
+    s := string(runes[start:end])
+    if start > 0 {
+        s = "…" + s
+    }
+    if end < len(runes) {
+        s += "…"
+    }
+
+    return s
+}
+
+// device describes the browser and operating system of the user agent string userAgent, such as
+// "Firefox on Linux". It returns userAgent itself if it doesn't recognise either.
+func device(userAgent string) string {
+    // Several browsers name the browsers they are based on too, so the order matters.
+    browsers := []struct{ token, name string }{
+        {"Edg/", "Edge"},
+        {"OPR/", "Opera"},
+        {"Firefox/", "Firefox"},
+        {"Chrome/", "Chrome"},
+        {"Safari/", "Safari"},
+    }
+    systems := []struct{ token, name string }{
+        {"Android", "Android"},
+        {"iPhone", "iOS"},
+        {"iPad", "iOS"},
+        {"Windows", "Windows"},
+        {"Mac OS X", "macOS"},
+        {"CrOS", "ChromeOS"},
+        {"Linux", "Linux"},
+    }
+
+    browser := ""
+    for _, b := range browsers {
+        if strings.Contains(userAgent, b.token) {
+            browser = b.name
+            break
+        }
+    }
+
+    system := ""
+    for _, s := range systems {
+        if strings.Contains(userAgent, s.token) {
+            system = s.name
+            break
+        }
+    }
+
+    switch {
+    case browser != "" && system != "":
+        return browser + " on " + system
+    case browser != "":
+        return browser
+    case system != "":
+        return system
+    case userAgent == "":
+        return "Unknown device"
+    default:
+        return userAgent
+    }
+}
+
+// mark escapes text and wraps the words of query in it in <mark> elements.
+func mark(text, query string) template.HTML {
+    rx := searchTermsRX(query)
+    if rx == nil {
+        return template.HTML(template.HTMLEscapeString(text))
+    }
+
+    var sb strings.Builder
+
+    last := 0
+    for _, loc := range rx.FindAllStringIndex(text, -1) {
+        sb.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+        sb.WriteString("<mark>" + template.HTMLEscapeString(text[loc[0]:loc[1]]) + "</mark>")
+        last = loc[1]
+    }
+    sb.WriteString(template.HTMLEscapeString(text[last:]))
+
+    return template.HTML(sb.String())
+}
+
 var functions = template.FuncMap{
-    "humanDate": humanDate,
+    "humanDate":         humanDate,
+    "highlight":         highlight.HTML,
+    "highlightAnchored": highlight.HTMLAnchored,
+    "markdown":          markdown.HTML,
+    "excerpt":           excerpt,
+    "mark":              mark,
+    "device":            device,
+    "languages":         func() []*highlight.Language { return highlight.Languages },
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -72,9 +306,10 @@ func newTemplateCache() (map[string]*template.Template, error) {
 
 func (app *application) newTemplateData(r *http.Request) templateData {
     return templateData{
-        CurrentYear:     time.Now().Year(),
-        IsAuthenticated: app.isAuthenticated(r),
-        CSRFToken:       nosurf.Token(r),
+        CurrentYear:         time.Now().Year(),
+        IsAuthenticated:     app.isAuthenticated(r),
+        AuthenticatedUserID: app.authenticatedUserID(r),
+        CSRFToken:           nosurf.Token(r),
         Flash: app.sessionManager.PopString(r.Context(), "flash"),  // Add the flash message to the template data, if one exists.
     }
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"snippetbox/internal/assert"
+	"snippetbox/internal/models"
+	"strings"
 	"testing"
 	"time"
 )
@@ -38,3 +41,131 @@ func TestHumanDate(t *testing.T) {
         })
     }
 }
+
+func TestMark(t *testing.T) {
+    tests := []struct {
+        name     string
+        text     string
+        query    string
+        expected string
+    }{
+        {
+            name:     "Match",
+            text:     "An old silent Pond...",
+            query:    "pond",
+            expected: "An old silent <mark>Pond</mark>...",
+        },
+        {
+            name:     "Several words",
+            text:     "A frog jumps into the pond",
+            query:    "frog, pond",
+            expected: "A <mark>frog</mark> jumps into the <mark>pond</mark>",
+        },
+        {
+            name:     "Escaped",
+            text:     "<b>pond</b>",
+            query:    "pond <b>",
+            expected: "&lt;b&gt;<mark>pond</mark>&lt;/b&gt;",
+        },
+        {
+            name:     "Short words",
+            text:     "a frog",
+            query:    "a",
+            expected: "a frog",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            assert.Equal(t, string(mark(tc.text, tc.query)), tc.expected)
+        })
+    }
+}
+
+func TestDevice(t *testing.T) {
+    tests := []struct {
+        name      string
+        userAgent string
+        expected  string
+    }{
+        {
+            name:      "Firefox",
+            userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+            expected:  "Firefox on Linux",
+        },
+        {
+            name:      "Edge",
+            userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0",
+            expected:  "Edge on Windows",
+        },
+        {
+            name:      "Safari on iPhone",
+            userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+            expected:  "Safari on iOS",
+        },
+        {
+            name:      "Chrome on Android",
+            userAgent: "Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+            expected:  "Chrome on Android",
+        },
+        {
+            name:      "Unknown",
+            userAgent: "curl/8.8.0",
+            expected:  "curl/8.8.0",
+        },
+        {
+            name:      "Empty",
+            userAgent: "",
+            expected:  "Unknown device",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            assert.Equal(t, device(tc.userAgent), tc.expected)
+        })
+    }
+}
+
+func TestExcerpt(t *testing.T) {
+    text := "0123456789 0123456789 pond 0123456789 0123456789"
+
+    assert.Equal(t, excerpt(text, "pond", 100), text)
+    assert.Equal(t, excerpt(text, "pond", 12), "…89 pond 0123…")
+    assert.Equal(t, excerpt(text, "frog", 10), "0123456789…")
+}
+
+func TestNewTagCloud(t *testing.T) {
+    cloud := newTagCloud([]models.Tag{{Name: "go", Count: 9}, {Name: "k8s", Count: 1}, {Name: "sql", Count: 5}})
+
+    assert.Equal(t, len(cloud), 3)
+    assert.Equal(t, cloud[0], cloudTag{Name: "go", Weight: 5})
+    assert.Equal(t, cloud[1], cloudTag{Name: "k8s", Weight: 1})
+    assert.Equal(t, cloud[2], cloudTag{Name: "sql", Weight: 3})
+
+    cloud = newTagCloud([]models.Tag{{Name: "go", Count: 2}})
+    assert.Equal(t, cloud[0].Weight, 1)
+}
+
+func TestThreadComments(t *testing.T) {
+    comments := []models.Comment{
+        {ID: 1},
+        {ID: 2, ParentID: 1},
+        {ID: 3},
+        {ID: 4, ParentID: 2},
+        {ID: 5, ParentID: 1},
+        {ID: 6, Deleted: time.Now()},
+        {ID: 7, ParentID: 3, Deleted: time.Now()},
+        {ID: 8, ParentID: 7},
+        {ID: 9, ParentID: 8},
+        {ID: 10, ParentID: 9},
+        {ID: 11, ParentID: 10},
+    }
+
+    var got []string
+    for _, c := range threadComments(comments) {
+        got = append(got, fmt.Sprintf("%d:%d", c.ID, c.Depth))
+    }
+
+    assert.Equal(t, strings.Join(got, " "), "1:0 2:1 4:2 5:1 3:0 7:1 8:2 9:3 10:4 11:4")
+}
@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"snippetbox/internal/mailer"
 	"snippetbox/internal/models/mocks"
 	"testing"
 	"time"
@@ -31,11 +32,20 @@ func newTestApplication(t *testing.T) *application {
     sessionManager.Cookie.Secure = true
 
     return &application{
-        logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
-        templateCache:  templateCache,
-        sessionManager: sessionManager,
-        user:           &mocks.UserModel{},
-        snippet:        &mocks.SnippetModel{},
+        logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+        templateCache:    templateCache,
+        sessionManager:   sessionManager,
+        user:             &mocks.UserModel{},
+        snippet:          &mocks.SnippetModel{},
+        comment:          &mocks.CommentModel{},
+        passwordReset:    &mocks.PasswordResetModel{},
+        loginFailure:     &mocks.LoginFailureModel{},
+        session:          &mocks.SessionModel{},
+        unlockLimiter:    newFailureLimiter(5, 15*time.Minute),
+        twoFactorLimiter: newFailureLimiter(5, 15*time.Minute),
+        mailer:           mailer.NewWriter(io.Discard, "no-reply@example.com"),
+        tokens:           newTokenSigner([]byte("0123456789abcdef0123456789abcdef")),
+        baseURL:          "https://snippetbox.example.com",
     }
 }
 
@@ -120,4 +130,47 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
     body = bytes.TrimSpace(body)
 
     return res.StatusCode, res.Header, string(body)
-}
\ No newline at end of file
+}
+
+// The login() helper logs the test server client in as the mock user "alice@example.com", so that
+// subsequent requests made with the client are authenticated. It returns a valid CSRF token for
+// the logged in session.
+func (ts *testServer) login(t *testing.T) string {
+    return ts.loginAs(t, "alice@example.com", "")
+}
+
+// loginAs logs the test server client in as the mock user with the email address email, whose
+// password is "pa$$word". otp is the one-time password entered if the user has turned on
+// two-factor authentication. It returns a valid CSRF token for the logged in session.
+func (ts *testServer) loginAs(t *testing.T, email, otp string) string {
+    _, _, body := ts.get(t, "/user/login")
+    csrfToken := extractCSRFToken(t, body)
+
+    form := url.Values{}
+    form.Add("email", email)
+    form.Add("password", "pa$$word")
+    form.Add("csrf_token", csrfToken)
+
+    code, header, _ := ts.postForm(t, "/user/login", form)
+    if code != http.StatusSeeOther {
+        t.Fatalf("login failed with status %d", code)
+    }
+
+    if header.Get("Location") == "/user/login/2fa" {
+        _, _, body = ts.get(t, "/user/login/2fa")
+
+        form = url.Values{}
+        form.Add("code", otp)
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, _, _ = ts.postForm(t, "/user/login/2fa", form)
+        if code != http.StatusSeeOther {
+            t.Fatalf("two-factor login failed with status %d", code)
+        }
+    }
+
+    // The session token is renewed at login, so fetch a fresh CSRF token from an authenticated page.
+    _, _, body = ts.get(t, "/snippet/create")
+
+    return extractCSRFToken(t, body)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Logins are throttled per client IP address and per account. The first few consecutive failures
+// are free. After that, each failure blocks further logins for twice as long as the one before,
+// and once there have been enough failures, every failure locks logins out for lockoutDuration.
+const (
+    freeLoginFailures      = 3
+    accountLockoutFailures = 10
+    ipLockoutFailures      = 50  // Higher than for accounts, as many users can share an address.
+    lockoutDuration        = 15 * time.Minute
+)
+
+// loginBackoff returns how long logins are blocked after failures consecutive failed logins by a
+// subject which is locked out after lockoutAfter failures, and whether that is a lockout.
+func loginBackoff(failures, lockoutAfter int) (time.Duration, bool) {
+    if failures >= lockoutAfter {
+        return lockoutDuration, true
+    }
+
+    if failures < freeLoginFailures {
+        return 0, false
+    }
+
+    // Limit the shift so that it can't overflow. The delay is capped anyway.
+    shift := min(failures-freeLoginFailures, 16)
+
+    return min(time.Second<<shift, lockoutDuration), false
+}
+
+// clientIP returns the IP address of the client making the request.
+func clientIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+
+    return host
+}
+
+// loginSubjects returns the subjects whose failed logins are counted when a login as the user with
+// the email address email is made by r. The account is identified by the email address, whether
+// or not it exists, so that throttling doesn't reveal which email addresses have accounts.
+func loginSubjects(r *http.Request, email string) (ip string, account string) {
+    return "ip:" + clientIP(r), "account:" + strings.ToLower(email)
+}
+
+// recordLoginFailure records a failed login as the user with the email address email and blocks
+// further logins by the client and to the account as needed. Lockouts are logged, and the owner of
+// an account is sent an email when it is first locked.
+func (app *application) recordLoginFailure(r *http.Request, email string) error {
+    ipSubject, accountSubject := loginSubjects(r, email)
+    ip := clientIP(r)
+
+    subjects := []struct {
+        subject      string
+        lockoutAfter int
+    }{
+        {subject: ipSubject, lockoutAfter: ipLockoutFailures},
+        {subject: accountSubject, lockoutAfter: accountLockoutFailures},
+    }
+
+    for _, s := range subjects {
+        failures, err := app.loginFailure.Fail(s.subject)
+        if err != nil {
+            return err
+        }
+
+        backoff, lockout := loginBackoff(failures, s.lockoutAfter)
+        if backoff == 0 {
+            continue
+        }
+
+        until := time.Now().Add(backoff)
+
+        err = app.loginFailure.Block(s.subject, until)
+        if err != nil {
+            return err
+        }
+
+        if lockout {
+            app.logger.Warn("login locked out", "subject", s.subject, "failures", failures, "until", until, "ip", ip)
+
+            // The failures aren't forgotten when a lockout ends, so each further failure locks the
+            // account again. The owner is only told about the first lockout, so that the emails
+            // can't be used to flood their inbox.
+            if s.subject == accountSubject && failures == s.lockoutAfter {
+                app.background(func() {
+                    err := app.sendLockoutEmail(email, ip, failures)
+                    if err != nil {
+                        app.logger.Error(err.Error())
+                    }
+                })
+            }
+        }
+    }
+
+    return nil
+}
+
+// retryText describes the time d which a user has to wait before they can try again.
+func retryText(d time.Duration) string {
+    if d < time.Minute {
+        return pluralize(int(math.Ceil(d.Seconds())), "second")
+    }
+
+    return pluralize(int(math.Ceil(d.Minutes())), "minute")
+}
+
+func pluralize(n int, unit string) string {
+    if n == 1 {
+        return fmt.Sprintf("1 %s", unit)
+    }
+
+    return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+    tests := []struct {
+        name          string
+        failures      int
+        expectBackoff time.Duration
+        expectLockout bool
+    }{
+        {
+            name: "First failure",
+            failures: 1,
+            expectBackoff: 0,
+        },
+        {
+            name: "Last free failure",
+            failures: freeLoginFailures - 1,
+            expectBackoff: 0,
+        },
+        {
+            name: "First backoff",
+            failures: freeLoginFailures,
+            expectBackoff: time.Second,
+        },
+        {
+            name: "Doubled backoff",
+            failures: freeLoginFailures + 3,
+            expectBackoff: 8 * time.Second,
+        },
+        {
+            name: "Lockout",
+            failures: accountLockoutFailures,
+            expectBackoff: lockoutDuration,
+            expectLockout: true,
+        },
+        {
+            name: "Capped backoff",
+            failures: ipLockoutFailures - 1,
+            expectBackoff: lockoutDuration,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            lockoutAfter := accountLockoutFailures
+            if tc.failures > accountLockoutFailures {
+                lockoutAfter = ipLockoutFailures
+            }
+
+            backoff, lockout := loginBackoff(tc.failures, lockoutAfter)
+
+            assert.Equal(t, backoff, tc.expectBackoff)
+            assert.Equal(t, lockout, tc.expectLockout)
+        })
+    }
+}
+
+func TestRetryText(t *testing.T) {
+    assert.Equal(t, retryText(1500*time.Millisecond), "2 seconds")
+    assert.Equal(t, retryText(time.Second), "1 second")
+    assert.Equal(t, retryText(61*time.Second), "2 minutes")
+    assert.Equal(t, retryText(lockoutDuration), "15 minutes")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// tokenSigner creates and checks signed tokens, which carry a value and the time until which they
+// are valid. A token can't be forged or altered without the key, but the value isn't secret.
+type tokenSigner struct {
+    key []byte
+}
+
+func newTokenSigner(key []byte) *tokenSigner {
+    return &tokenSigner{key: key}
+}
+
+// mac returns the HMAC-SHA256 of the encoded value and expiry time of a token.
+func (s *tokenSigner) mac(payload string) []byte {
+    h := hmac.New(sha256.New, s.key)
+    h.Write([]byte(payload))
+
+    return h.Sum(nil)
+}
+
+// Sign returns a URL-safe token for value which is valid until expires.
+func (s *tokenSigner) Sign(value string, expires time.Time) string {
+    payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expires.Unix(), 10)
+
+    return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// Verify returns the value of token if it was created by Sign with the same key and is still
+// valid at now. Otherwise it returns errInvalidToken.
+func (s *tokenSigner) Verify(token string, now time.Time) (string, error) {
+    i := strings.LastIndexByte(token, '.')
+    if i < 0 {
+        return "", errInvalidToken
+    }
+    payload := token[:i]
+
+    mac, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+    if err != nil || !hmac.Equal(mac, s.mac(payload)) {
+        return "", errInvalidToken
+    }
+
+    // The payload was signed by us, so it is well-formed.
+    encoded, expires, _ := strings.Cut(payload, ".")
+
+    unix, err := strconv.ParseInt(expires, 10, 64)
+    if err != nil || !now.Before(time.Unix(unix, 0)) {
+        return "", errInvalidToken
+    }
+
+    value, err := base64.RawURLEncoding.DecodeString(encoded)
+    if err != nil {
+        return "", errInvalidToken
+    }
+
+    return string(value), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"snippetbox/internal/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenSigner(t *testing.T) {
+    s := newTokenSigner([]byte("0123456789abcdef0123456789abcdef"))
+    now := time.Now()
+
+    token := s.Sign("verify:1:alice@example.com", now.Add(time.Hour))
+
+    value, err := s.Verify(token, now)
+    assert.NilError(t, err)
+    assert.Equal(t, value, "verify:1:alice@example.com")
+
+    t.Run("Expired", func(t *testing.T) {
+        _, err := s.Verify(token, now.Add(2*time.Hour))
+        assert.Equal(t, err, errInvalidToken)
+    })
+
+    t.Run("Other key", func(t *testing.T) {
+        other := newTokenSigner([]byte("fedcba9876543210fedcba9876543210"))
+
+        _, err := other.Verify(token, now)
+        assert.Equal(t, err, errInvalidToken)
+    })
+
+    t.Run("Altered expiry", func(t *testing.T) {
+        parts := strings.Split(token, ".")
+        parts[1] = "99999999999"
+
+        _, err := s.Verify(strings.Join(parts, "."), now)
+        assert.Equal(t, err, errInvalidToken)
+    })
+
+    t.Run("Malformed", func(t *testing.T) {
+        for _, token := range []string{"", "abc", "a.b.c", "..."} {
+            _, err := s.Verify(token, now)
+            assert.Equal(t, err, errInvalidToken)
+        }
+    })
+}
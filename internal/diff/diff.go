@@ -0,0 +1,356 @@
+// Package diff computes line-based differences between two texts using the Myers algorithm and
+// formats them as unified diffs or side-by-side rows.
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Op is the kind of change a Line represents.
+type Op int
+
+const (
+    Equal Op = iota
+    Delete
+    Insert
+)
+
+// String returns the name of op. It is used as a CSS class when rendering diffs.
+func (op Op) String() string {
+    switch op {
+    case Delete:
+        return "delete"
+    case Insert:
+        return "insert"
+    default:
+        return "equal"
+    }
+}
+
+// Line is a single line of an edit script. Old and New hold the 1-based line numbers of the line
+// in the old and new texts, or 0 if the line does not appear in that text.
+type Line struct {
+    Op   Op
+    Old  int
+    New  int
+    Text string
+}
+
+// Prefix returns the character which precedes the line in a unified diff.
+func (l Line) Prefix() string {
+    switch l.Op {
+    case Delete:
+        return "-"
+    case Insert:
+        return "+"
+    default:
+        return " "
+    }
+}
+
+// Hunk is a group of changed lines together with their surrounding context lines.
+type Hunk struct {
+    OldStart int
+    OldLines int
+    NewStart int
+    NewLines int
+    Lines    []Line
+}
+
+// Header returns the "@@ -l,s +l,s @@" range information of the hunk.
+func (h Hunk) Header() string {
+    return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Row is a row of a side-by-side diff. A zero Line on either side means the row has no line in
+// that text.
+type Row struct {
+    Left  Line
+    Right Line
+}
+
+// Rows pairs the lines of the hunk for a side-by-side view. Consecutive deletions and insertions
+// are shown next to each other.
+func (h Hunk) Rows() []Row {
+    var (
+        rows    []Row
+        deletes []Line
+        inserts []Line
+    )
+
+    flush := func() {
+        for i := 0; i < max(len(deletes), len(inserts)); i++ {
+            var row Row
+            if i < len(deletes) {
+                row.Left = deletes[i]
+            }
+            if i < len(inserts) {
+                row.Right = inserts[i]
+            }
+            rows = append(rows, row)
+        }
+        deletes, inserts = deletes[:0], inserts[:0]
+    }
+
+    for _, l := range h.Lines {
+        switch l.Op {
+        case Delete:
+            deletes = append(deletes, l)
+        case Insert:
+            inserts = append(inserts, l)
+        default:
+            flush()
+            rows = append(rows, Row{Left: l, Right: l})
+        }
+    }
+    flush()
+
+    return rows
+}
+
+// SplitLines splits s into lines. Windows line endings are normalised and a trailing newline does
+// not produce an empty final line.
+func SplitLines(s string) []string {
+    s = strings.ReplaceAll(s, "\r\n", "\n")
+    if s == "" {
+        return nil
+    }
+
+    return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Lines returns the shortest edit script which turns text a into text b.
+func Lines(a, b string) []Line {
+    return Myers(SplitLines(a), SplitLines(b))
+}
+
+// maxEditDistance bounds the work done by Myers. The rounds saved to recover the path take memory
+// in proportion to the square of the edit distance, so texts which differ by more lines than this
+// are diffed by replace instead.
+const maxEditDistance = 1000
+
+// Myers returns the shortest edit script which turns a into b, computed with the greedy algorithm
+// described in "An O(ND) Difference Algorithm and Its Variations" by Eugene W. Myers. If more than
+// maxEditDistance lines have to be deleted or inserted, it returns the script of replace instead.
+func Myers(a, b []string) []Line {
+    n, m := len(a), len(b)
+    offset := n + m
+
+    // v[offset+k] holds the furthest x reached on diagonal k. Before each round d the part of v
+    // for diagonals -d to d is saved, so that the path can be recovered afterwards.
+    v := make([]int, 2*(n+m)+2)
+    var trace [][]int
+
+search:
+    for d := 0; d <= n+m; d++ {
+        if d > maxEditDistance {
+            return replace(a, b)
+        }
+
+        trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+
+        for k := -d; k <= d; k += 2 {
+            var x int
+            if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+                x = v[offset+k+1]
+            } else {
+                x = v[offset+k-1] + 1
+            }
+            y := x - k
+
+            for x < n && y < m && a[x] == b[y] {
+                x++
+                y++
+            }
+            v[offset+k] = x
+
+            if x >= n && y >= m {
+                break search
+            }
+        }
+    }
+
+    // Walk back from the end of both texts through the saved rounds. Diagonal k of round d is
+    // saved at index d+k.
+    var lines []Line
+    x, y := n, m
+
+    for d := len(trace) - 1; d >= 0; d-- {
+        v := trace[d]
+        k := x - y
+
+        // Round 0 starts at the beginning of both texts.
+        prevX, prevY := 0, 0
+        if d > 0 {
+            var prevK int
+            if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+                prevK = k + 1
+            } else {
+                prevK = k - 1
+            }
+            prevX = v[d+prevK]
+            prevY = prevX - prevK
+        }
+
+        for x > prevX && y > prevY {
+            lines = append(lines, Line{Op: Equal, Old: x, New: y, Text: a[x-1]})
+            x--
+            y--
+        }
+
+        if d > 0 {
+            if x == prevX {
+                lines = append(lines, Line{Op: Insert, New: y, Text: b[y-1]})
+            } else {
+                lines = append(lines, Line{Op: Delete, Old: x, Text: a[x-1]})
+            }
+        }
+
+        x, y = prevX, prevY
+    }
+
+    slices.Reverse(lines)
+
+    return lines
+}
+
+// replace returns an edit script which keeps the lines a and b start and end with, and deletes all
+// the other lines of a and inserts all the other lines of b. It takes linear time and space, but
+// is not the shortest script.
+func replace(a, b []string) []Line {
+    prefix := 0
+    for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+        prefix++
+    }
+
+    suffix := 0
+    for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+        suffix++
+    }
+
+    lines := make([]Line, 0, len(a)+len(b)-prefix-suffix)
+
+    for i := 0; i < prefix; i++ {
+        lines = append(lines, Line{Op: Equal, Old: i + 1, New: i + 1, Text: a[i]})
+    }
+    for i := prefix; i < len(a)-suffix; i++ {
+        lines = append(lines, Line{Op: Delete, Old: i + 1, Text: a[i]})
+    }
+    for j := prefix; j < len(b)-suffix; j++ {
+        lines = append(lines, Line{Op: Insert, New: j + 1, Text: b[j]})
+    }
+    for i := len(a) - suffix; i < len(a); i++ {
+        j := i - len(a) + len(b)
+        lines = append(lines, Line{Op: Equal, Old: i + 1, New: j + 1, Text: a[i]})
+    }
+
+    return lines
+}
+
+// Hunks groups the changes in lines into hunks with up to context unchanged lines before and
+// after each change. It returns nil if the texts are identical.
+func Hunks(lines []Line, context int) []Hunk {
+    var hunks []Hunk
+
+    i := 0
+    for i < len(lines) {
+        // Find the next change.
+        for i < len(lines) && lines[i].Op == Equal {
+            i++
+        }
+        if i == len(lines) {
+            break
+        }
+
+        start := max(i-context, 0)
+
+        // Extend the hunk until the gap between two changes is larger than twice the context, so
+        // that the context lines of neighbouring hunks never overlap.
+        end := i
+        for end < len(lines) {
+            if lines[end].Op != Equal {
+                end++
+                continue
+            }
+
+            next := end
+            for next < len(lines) && lines[next].Op == Equal {
+                next++
+            }
+            if next == len(lines) || next-end > 2*context {
+                end = min(end+context, len(lines))
+                break
+            }
+            end = next
+        }
+
+        hunks = append(hunks, newHunk(lines, start, end))
+        i = end
+    }
+
+    return hunks
+}
+
+func newHunk(lines []Line, start, end int) Hunk {
+    h := Hunk{Lines: lines[start:end:end]}
+
+    // The number of lines of each text which come before the hunk.
+    var oldBefore, newBefore int
+    for _, l := range lines[:start] {
+        if l.Op != Insert {
+            oldBefore++
+        }
+        if l.Op != Delete {
+            newBefore++
+        }
+    }
+
+    for _, l := range h.Lines {
+        if l.Op != Insert {
+            h.OldLines++
+        }
+        if l.Op != Delete {
+            h.NewLines++
+        }
+    }
+
+    // As in GNU diff, an empty range starts at the line before the hunk.
+    h.OldStart = oldBefore + 1
+    if h.OldLines == 0 {
+        h.OldStart = oldBefore
+    }
+    h.NewStart = newBefore + 1
+    if h.NewLines == 0 {
+        h.NewStart = newBefore
+    }
+
+    return h
+}
+
+// Unified formats hunks as a unified diff which can be applied with patch(1). oldName and newName
+// are used in the "---" and "+++" header lines.
+func Unified(oldName, newName string, hunks []Hunk) string {
+    if len(hunks) == 0 {
+        return ""
+    }
+
+    var sb strings.Builder
+
+    fmt.Fprintf(&sb, "--- %s\n", oldName)
+    fmt.Fprintf(&sb, "+++ %s\n", newName)
+
+    for _, h := range hunks {
+        sb.WriteString(h.Header())
+        sb.WriteByte('\n')
+
+        for _, l := range h.Lines {
+            sb.WriteString(l.Prefix())
+            sb.WriteString(l.Text)
+            sb.WriteByte('\n')
+        }
+    }
+
+    return sb.String()
+}
@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"fmt"
+	"snippetbox/internal/assert"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+    tests := []struct {
+        name   string
+        a      string
+        b      string
+        expect string
+    }{
+        {
+            name:   "Identical",
+            a:      "a\nb\nc\n",
+            b:      "a\nb\nc\n",
+            expect: " a b c",
+        },
+        {
+            name:   "Empty",
+            a:      "",
+            b:      "",
+            expect: "",
+        },
+        {
+            name:   "All inserted",
+            a:      "",
+            b:      "a\nb",
+            expect: "+a+b",
+        },
+        {
+            name:   "All deleted",
+            a:      "a\nb",
+            b:      "",
+            expect: "-a-b",
+        },
+        {
+            name:   "Changed line",
+            a:      "a\nb\nc",
+            b:      "a\nx\nc",
+            expect: " a-b+x c",
+        },
+        {
+            name:   "Myers paper example",
+            a:      "A\nB\nC\nA\nB\nB\nA",
+            b:      "C\nB\nA\nB\nA\nC",
+            expect: "-A-B C+B A B-B A+C",
+        },
+        {
+            name:   "Windows line endings",
+            a:      "a\r\nb\r\n",
+            b:      "a\nb\n",
+            expect: " a b",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            var sb strings.Builder
+            for _, l := range Lines(tc.a, tc.b) {
+                sb.WriteString(l.Prefix() + l.Text)
+            }
+
+            assert.Equal(t, sb.String(), tc.expect)
+        })
+    }
+}
+
+func TestMyersMaxEditDistance(t *testing.T) {
+    // Two texts with no lines in common between the first and last lines differ by more lines
+    // than maxEditDistance.
+    a := []string{"first"}
+    b := []string{"first"}
+    for i := 0; i < maxEditDistance; i++ {
+        a = append(a, fmt.Sprintf("a%d", i))
+        b = append(b, fmt.Sprintf("b%d", i))
+    }
+    a = append(a, "last")
+    b = append(b, "last")
+
+    lines := Myers(a, b)
+
+    assert.Equal(t, len(lines), 2*maxEditDistance+2)
+    assert.Equal(t, lines[0], Line{Op: Equal, Old: 1, New: 1, Text: "first"})
+    assert.Equal(t, lines[1], Line{Op: Delete, Old: 2, Text: "a0"})
+    assert.Equal(t, lines[maxEditDistance], Line{Op: Delete, Old: maxEditDistance + 1, Text: fmt.Sprintf("a%d", maxEditDistance-1)})
+    assert.Equal(t, lines[maxEditDistance+1], Line{Op: Insert, New: 2, Text: "b0"})
+    assert.Equal(t, lines[len(lines)-1], Line{Op: Equal, Old: maxEditDistance + 2, New: maxEditDistance + 2, Text: "last"})
+
+    // Texts which differ by fewer lines still get the shortest script.
+    lines = Myers(a[:maxEditDistance/2], b[:maxEditDistance/2])
+
+    assert.Equal(t, len(lines), maxEditDistance-1)
+    assert.Equal(t, lines[0].Op, Equal)
+}
+
+func TestUnified(t *testing.T) {
+    a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+    b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+    expect := `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+
+    got := Unified("a", "b", Hunks(Lines(a, b), 3))
+
+    assert.Equal(t, got, expect)
+}
+
+func TestUnifiedIdentical(t *testing.T) {
+    got := Unified("a", "b", Hunks(Lines("same\n", "same\n"), 3))
+
+    assert.Equal(t, got, "")
+}
+
+func TestRows(t *testing.T) {
+    hunks := Hunks(Lines("a\nb\nc\nd", "a\nx\ny\nd"), 1)
+
+    assert.Equal(t, len(hunks), 1)
+
+    rows := hunks[0].Rows()
+
+    assert.Equal(t, len(rows), 4)
+    assert.Equal(t, rows[1].Left.Text, "b")
+    assert.Equal(t, rows[1].Right.Text, "x")
+    assert.Equal(t, rows[2].Left.Text, "c")
+    assert.Equal(t, rows[2].Right.Text, "y")
+    assert.Equal(t, rows[3].Left.Op, Equal)
+}
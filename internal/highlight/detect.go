@@ -0,0 +1,125 @@
+package highlight
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// signatures holds patterns which are typical of each language. The more of them a text
+// matches, the more likely it is to be written in that language.
+var signatures = []struct {
+    language string
+    patterns []*regexp.Regexp
+}{
+    {"go", compile(
+        `^package \w+$`,
+        `^func (\(\w+ \*?\w+\) )?\w+\(`,
+        `\w+ := `,
+        `^import \($`,
+        `\berr != nil\b`,
+    )},
+    {"python", compile(
+        `^\s*def \w+\(.*\):$`,
+        `^\s*(from [\w.]+ )?import \w+`,
+        `^\s*class \w+(\(.*\))?:$`,
+        `\bself\.\w+`,
+        `^\s*(elif|except|with) .*:$`,
+        `^if __name__ == .__main__.:`,
+    )},
+    {"javascript", compile(
+        `\bfunction\s*\w*\(`,
+        `\b(const|let|var) \w+ = `,
+        `\) => `,
+        `\bconsole\.\w+\(`,
+        ` === `,
+        `\brequire\(['"]`,
+    )},
+    {"sql", compile(
+        `(?i)\bSELECT\b.+\bFROM\b`,
+        `(?i)\bINSERT INTO\b`,
+        `(?i)\bCREATE (TABLE|INDEX|DATABASE)\b`,
+        `(?i)\bUPDATE \w+ SET\b`,
+        `(?i)\bWHERE \w+`,
+        `;$`,
+    )},
+    {"shell", compile(
+        `^#!/.*\b(ba|z)?sh\b`,
+        `^\s*(echo|export|cd|sudo|apt(-get)?|curl|grep|mkdir|chmod) `,
+        `\$\{?\w+\}?`,
+        `^\s*(fi|done|esac)$`,
+        `\s\|\s\w+`,
+    )},
+    {"yaml", compile(
+        `^---\s*$`,
+        `^[\w-]+:\s*$`,
+        `^\s+[\w-]+: \S`,
+        `^\s*- [\w-]+: `,
+    )},
+}
+
+func compile(patterns ...string) []*regexp.Regexp {
+    rxs := make([]*regexp.Regexp, len(patterns))
+    for i, p := range patterns {
+        rxs[i] = regexp.MustCompile("(?m)" + p)
+    }
+
+    return rxs
+}
+
+// Detect guesses the language which src is written in. It returns Text if no language matches
+// with reasonable confidence.
+func Detect(src string) string {
+    src = strings.ReplaceAll(src, "\r\n", "\n")
+    trimmed := strings.TrimSpace(src)
+
+    if strings.HasPrefix(trimmed, "#!") {
+        shebang, _, _ := strings.Cut(trimmed, "\n")
+        switch {
+        case strings.Contains(shebang, "python"):
+            return "python"
+        case strings.Contains(shebang, "node"):
+            return "javascript"
+        case strings.HasSuffix(shebang, "sh"):
+            return "shell"
+        }
+    }
+
+    if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+        return "json"
+    }
+
+    // At least two different patterns must match, so that prose which happens to contain a
+    // single code-like phrase stays plain text.
+    best, bestScore := Text, 1
+
+    for _, sig := range signatures {
+        score := 0
+        for _, rx := range sig.patterns {
+            if rx.MatchString(src) {
+                score++
+            }
+        }
+
+        if score > bestScore {
+            best, bestScore = sig.language, score
+        }
+    }
+
+    return best
+}
+
+// DetectFile guesses the language of the file called name with content src. The extension of the
+// name is used if it is known, otherwise the content is examined as by Detect.
+func DetectFile(name, src string) string {
+    if ext := strings.ToLower(path.Ext(name)); ext != "" {
+        for _, l := range Languages {
+            if l.Extension == ext {
+                return l.Name
+            }
+        }
+    }
+
+    return Detect(src)
+}
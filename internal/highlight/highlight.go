@@ -0,0 +1,306 @@
+// Package highlight renders source code as HTML with syntax highlighting. Tokens are wrapped in
+// spans with CSS classes rather than inline styles, so the output works under a strict
+// Content-Security-Policy, and each line gets an anchor of the form "L12".
+package highlight
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Text is the name of the language which has no highlighting.
+const Text = "text"
+
+// delim describes a token which starts and ends with fixed delimiters, such as a string or a block
+// comment.
+type delim struct {
+    start     string
+    end       string
+    escape    bool  // Whether a backslash escapes the next character.
+    multiline bool  // Whether the token may span several lines.
+}
+
+// Language describes how the source code of a programming language is split into tokens.
+type Language struct {
+    Name            string  // The name stored with a snippet, e.g. "go".
+    Label           string  // The name shown to users, e.g. "Go".
+    Extension       string  // The usual file name extension, e.g. ".go".
+    keywords        map[string]bool
+    caseInsensitive bool  // Whether keywords are matched regardless of case, as in SQL.
+    lineComments    []string
+    blockComments   []delim
+    strings         []delim  // Tried in order, so longer delimiters must come first.
+}
+
+func newLanguage(name, label, extension, keywords string) *Language {
+    l := &Language{
+        Name:      name,
+        Label:     label,
+        Extension: extension,
+        keywords:  make(map[string]bool),
+    }
+
+    for _, k := range strings.Fields(keywords) {
+        l.keywords[k] = true
+    }
+
+    return l
+}
+
+var (
+    cStyleComments = []delim{{start: "/*", end: "*/", multiline: true}}
+    quotedStrings  = []delim{
+        {start: `"`, end: `"`, escape: true},
+        {start: `'`, end: `'`, escape: true},
+    }
+)
+
+// Languages lists the supported languages in the order they are offered to users.
+var Languages = func() []*Language {
+    text := newLanguage(Text, "Plain text", ".txt", "")
+
+    golang := newLanguage("go", "Go", ".go", `break case chan const continue default defer else fallthrough
+        for func go goto if import interface map package range return select struct switch type var
+        true false nil iota`)
+    golang.lineComments = []string{"//"}
+    golang.blockComments = cStyleComments
+    golang.strings = append([]delim{{start: "`", end: "`", multiline: true}}, quotedStrings...)
+
+    python := newLanguage("python", "Python", ".py", `and as assert async await break class continue def del
+        elif else except finally for from global if import in is lambda nonlocal not or pass raise
+        return try while with yield True False None`)
+    python.lineComments = []string{"#"}
+    python.strings = append([]delim{
+        {start: `"""`, end: `"""`, escape: true, multiline: true},
+        {start: `'''`, end: `'''`, escape: true, multiline: true},
+    }, quotedStrings...)
+
+    javascript := newLanguage("javascript", "JavaScript", ".js", `async await break case catch class const
+        continue debugger default delete do else export extends finally for function if import in
+        instanceof let new of return super switch this throw try typeof var void while yield true
+        false null undefined`)
+    javascript.lineComments = []string{"//"}
+    javascript.blockComments = cStyleComments
+    javascript.strings = append([]delim{{start: "`", end: "`", escape: true, multiline: true}}, quotedStrings...)
+
+    sql := newLanguage("sql", "SQL", ".sql", `ADD ALL ALTER AND AS ASC BEGIN BETWEEN BY CASE COMMIT CONSTRAINT
+        CREATE DATABASE DEFAULT DELETE DESC DISTINCT DROP ELSE END EXISTS FALSE FOREIGN FROM GROUP
+        HAVING IN INDEX INNER INSERT INTO IS JOIN KEY LEFT LIKE LIMIT NOT NULL OFFSET ON OR ORDER
+        OUTER PRIMARY REFERENCES RIGHT ROLLBACK SELECT SET TABLE THEN TRUE UNION UNIQUE UPDATE USE
+        VALUES WHEN WHERE`)
+    sql.caseInsensitive = true
+    sql.lineComments = []string{"--", "#"}
+    sql.blockComments = cStyleComments
+    sql.strings = []delim{
+        {start: `'`, end: `'`, escape: true, multiline: true},
+        {start: `"`, end: `"`, escape: true},
+        {start: "`", end: "`"},
+    }
+
+    shell := newLanguage("shell", "Shell", ".sh", `case do done elif else esac export fi for function if in
+        local readonly return then until while`)
+    shell.lineComments = []string{"#"}
+    shell.strings = []delim{
+        {start: `"`, end: `"`, escape: true, multiline: true},
+        {start: `'`, end: `'`, multiline: true},
+    }
+
+    json := newLanguage("json", "JSON", ".json", "true false null")
+    json.strings = []delim{{start: `"`, end: `"`, escape: true}}
+
+    yaml := newLanguage("yaml", "YAML", ".yaml", "true false null yes no on off")
+    yaml.lineComments = []string{"#"}
+    yaml.strings = quotedStrings
+
+    return []*Language{text, golang, python, javascript, sql, shell, json, yaml}
+}()
+
+// Lookup returns the language called name, or nil if it isn't supported.
+func Lookup(name string) *Language {
+    for _, l := range Languages {
+        if l.Name == name {
+            return l
+        }
+    }
+
+    return nil
+}
+
+// token is a piece of source code. Class is empty for text which isn't highlighted.
+type token struct {
+    class string
+    text  string
+}
+
+// tokenize splits src into tokens according to the rules of l.
+func (l *Language) tokenize(src string) []token {
+    if l.Name == Text {
+        return []token{{text: src}}
+    }
+
+    var tokens []token
+
+    // plain is the start of the text which isn't highlighted and hasn't been added to tokens yet.
+    plain := 0
+
+    emit := func(class string, start, end int) {
+        if plain < start {
+            tokens = append(tokens, token{text: src[plain:start]})
+        }
+        tokens = append(tokens, token{class: class, text: src[start:end]})
+        plain = end
+    }
+
+    i := 0
+
+next:
+    for i < len(src) {
+        rest := src[i:]
+
+        for _, prefix := range l.lineComments {
+            if strings.HasPrefix(rest, prefix) {
+                end := strings.IndexByte(rest, '\n')
+                if end < 0 {
+                    end = len(rest)
+                }
+                emit("comment", i, i+end)
+                i += end
+                continue next
+            }
+        }
+
+        for _, d := range l.blockComments {
+            if strings.HasPrefix(rest, d.start) {
+                n := d.match(rest)
+                emit("comment", i, i+n)
+                i += n
+                continue next
+            }
+        }
+
+        for _, d := range l.strings {
+            if strings.HasPrefix(rest, d.start) {
+                n := d.match(rest)
+                emit("string", i, i+n)
+                i += n
+                continue next
+            }
+        }
+
+        c := src[i]
+
+        switch {
+        case isDigit(c) && (i == 0 || !isWordByte(src[i-1])):
+            n := 1
+            for n < len(rest) && (isWordByte(rest[n]) || rest[n] == '.') {
+                n++
+            }
+            emit("number", i, i+n)
+            i += n
+        case isWordByte(c):
+            n := 1
+            for n < len(rest) && isWordByte(rest[n]) {
+                n++
+            }
+            if l.isKeyword(rest[:n]) {
+                emit("keyword", i, i+n)
+            }
+            i += n
+        default:
+            i++
+        }
+    }
+
+    if plain < len(src) {
+        tokens = append(tokens, token{text: src[plain:]})
+    }
+
+    return tokens
+}
+
+// match returns the length of the token delimited by d at the start of s. An unterminated token
+// runs to the end of the line, or to the end of s if it may span several lines.
+func (d delim) match(s string) int {
+    i := len(d.start)
+
+    for i < len(s) {
+        switch {
+        case d.escape && s[i] == '\\' && i+1 < len(s):
+            i += 2
+        case strings.HasPrefix(s[i:], d.end):
+            return i + len(d.end)
+        case s[i] == '\n' && !d.multiline:
+            return i
+        default:
+            i++
+        }
+    }
+
+    return len(s)
+}
+
+func (l *Language) isKeyword(word string) bool {
+    if l.caseInsensitive {
+        word = strings.ToUpper(word)
+    }
+
+    return l.keywords[word]
+}
+
+func isDigit(c byte) bool {
+    return '0' <= c && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+    return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// HTML returns src highlighted as language. Unknown languages are rendered as plain text. Every
+// line is wrapped in a span with the class "line" and the ID "L" followed by the line number, and
+// tokens are wrapped in spans with classes such as "hl-keyword" and "hl-string".
+func HTML(src, language string) template.HTML {
+    return HTMLAnchored(src, language, "L")
+}
+
+// HTMLAnchored is like HTML, but the IDs of the lines start with anchor instead of "L". It is used
+// when several texts are shown on the same page.
+func HTMLAnchored(src, language, anchor string) template.HTML {
+    l := Lookup(language)
+    if l == nil {
+        l = Lookup(Text)
+    }
+
+    src = strings.ReplaceAll(src, "\r\n", "\n")
+    src = strings.TrimSuffix(src, "\n")
+
+    var sb strings.Builder
+
+    line := 1
+    fmt.Fprintf(&sb, `<span class="line" id="%s1">`, template.HTMLEscapeString(anchor))
+
+    for _, t := range l.tokenize(src) {
+        for i, part := range strings.Split(t.text, "\n") {
+            // Tokens which span several lines are closed at the end of each line and reopened on
+            // the next one, so that every line span is well-formed.
+            if i > 0 {
+                line++
+                fmt.Fprintf(&sb, "</span>\n<span class=\"line\" id=\"%s%d\">", template.HTMLEscapeString(anchor), line)
+            }
+
+            if part == "" {
+                continue
+            }
+
+            if t.class == "" {
+                sb.WriteString(template.HTMLEscapeString(part))
+            } else {
+                fmt.Fprintf(&sb, `<span class="hl-%s">%s</span>`, t.class, template.HTMLEscapeString(part))
+            }
+        }
+    }
+
+    sb.WriteString("</span>")
+
+    return template.HTML(sb.String())
+}
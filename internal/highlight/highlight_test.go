@@ -0,0 +1,137 @@
+package highlight
+
+import (
+	"snippetbox/internal/assert"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+    tests := []struct {
+        name     string
+        src      string
+        language string
+        want     string
+    }{
+        {
+            name:     "Go",
+            src:      "func main() {\n\treturn 42 // done\n}\n",
+            language: "go",
+            want: `<span class="line" id="L1"><span class="hl-keyword">func</span> main() {</span>` + "\n" +
+                `<span class="line" id="L2">	<span class="hl-keyword">return</span> <span class="hl-number">42</span> <span class="hl-comment">// done</span></span>` + "\n" +
+                `<span class="line" id="L3">}</span>`,
+        },
+        {
+            name:     "Escaped string",
+            src:      `s := "a \"quoted\" <b>"`,
+            language: "go",
+            want:     `<span class="line" id="L1">s := <span class="hl-string">&#34;a \&#34;quoted\&#34; &lt;b&gt;&#34;</span></span>`,
+        },
+        {
+            name:     "Multi-line comment",
+            src:      "/* one\ntwo */ x",
+            language: "javascript",
+            want: `<span class="line" id="L1"><span class="hl-comment">/* one</span></span>` + "\n" +
+                `<span class="line" id="L2"><span class="hl-comment">two */</span> x</span>`,
+        },
+        {
+            name:     "Case-insensitive keywords",
+            src:      "select id from snippet",
+            language: "sql",
+            want:     `<span class="line" id="L1"><span class="hl-keyword">select</span> id <span class="hl-keyword">from</span> snippet</span>`,
+        },
+        {
+            name:     "Unterminated string",
+            src:      "x = 'oops\ny = 1",
+            language: "python",
+            want: `<span class="line" id="L1">x = <span class="hl-string">&#39;oops</span></span>` + "\n" +
+                `<span class="line" id="L2">y = <span class="hl-number">1</span></span>`,
+        },
+        {
+            name:     "Plain text",
+            src:      "<script>alert(1)</script>\r\nfor ever",
+            language: "text",
+            want: `<span class="line" id="L1">&lt;script&gt;alert(1)&lt;/script&gt;</span>` + "\n" +
+                `<span class="line" id="L2">for ever</span>`,
+        },
+        {
+            name:     "Unknown language",
+            src:      "if x",
+            language: "cobol",
+            want:     `<span class="line" id="L1">if x</span>`,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            assert.Equal(t, string(HTML(tc.src, tc.language)), tc.want)
+        })
+    }
+}
+
+func TestHTMLAnchored(t *testing.T) {
+    got := HTMLAnchored("a\nb", Text, "F2-L")
+    want := `<span class="line" id="F2-L1">a</span>` + "\n" + `<span class="line" id="F2-L2">b</span>`
+
+    assert.Equal(t, string(got), want)
+}
+
+func TestDetect(t *testing.T) {
+    tests := []struct {
+        name string
+        src  string
+        want string
+    }{
+        {
+            name: "Go",
+            src:  "package main\n\nfunc main() {\n    x := 1\n}\n",
+            want: "go",
+        },
+        {
+            name: "Python",
+            src:  "import os\n\ndef main():\n    print(os.getcwd())\n",
+            want: "python",
+        },
+        {
+            name: "JavaScript",
+            src:  "const add = (a, b) => a + b;\nconsole.log(add(1, 2));\n",
+            want: "javascript",
+        },
+        {
+            name: "SQL",
+            src:  "SELECT id, title\n  FROM snippet\n WHERE id = 1;\n",
+            want: "sql",
+        },
+        {
+            name: "Shell shebang",
+            src:  "#!/bin/bash\nls\n",
+            want: "shell",
+        },
+        {
+            name: "JSON",
+            src:  `{"name": "snippetbox", "tags": ["go"]}`,
+            want: "json",
+        },
+        {
+            name: "YAML",
+            src:  "apiVersion: v1\nmetadata:\n  name: web\n",
+            want: "yaml",
+        },
+        {
+            name: "Prose",
+            src:  "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+            want: Text,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            assert.Equal(t, Detect(tc.src), tc.want)
+        })
+    }
+}
+
+func TestDetectFile(t *testing.T) {
+    assert.Equal(t, DetectFile("deploy.SH", "kubectl apply"), "shell")
+    assert.Equal(t, DetectFile("notes", "SELECT id FROM snippet WHERE id = 1;"), "sql")
+    assert.Equal(t, DetectFile("README", "Read me."), Text)
+}
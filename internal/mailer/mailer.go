@@ -0,0 +1,130 @@
+// Package mailer sends emails to users. Emails are sent through an SMTP server in production,
+// and written to an io.Writer such as os.Stdout during development and in tests.
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidHeader is returned when an address or the subject of a message contains a line
+// break, which could be used to inject further headers into the message.
+var ErrInvalidHeader = errors.New("mailer: invalid header")
+
+// Message is a plain text email.
+type Message struct {
+    To      string
+    Subject string
+    Body    string
+}
+
+// Mailer is implemented by the ways of sending emails.
+type Mailer interface {
+    Send(msg Message) error
+}
+
+// format returns msg in the Internet Message Format of RFC 5322, as sent by from.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+    for _, h := range []string{from, msg.To, msg.Subject} {
+        if strings.ContainsAny(h, "\r\n") {
+            return nil, ErrInvalidHeader
+        }
+    }
+
+    var b bytes.Buffer
+
+    fmt.Fprintf(&b, "From: %s\r\n", from)
+    fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+    fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+    fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+    b.WriteString("MIME-Version: 1.0\r\n")
+    b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+    b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+    b.WriteString("\r\n")
+
+    body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+    b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+    return b.Bytes(), nil
+}
+
+// SMTP sends emails through an SMTP server.
+type SMTP struct {
+    addr   string
+    auth   smtp.Auth
+    sender string
+}
+
+// NewSMTP returns a Mailer which sends emails as sender through the SMTP server at host:port. If
+// username is not empty, it authenticates with username and password, which the smtp package
+// only does over TLS or to localhost.
+func NewSMTP(host string, port int, username, password, sender string) *SMTP {
+    m := &SMTP{
+        addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+        sender: sender,
+    }
+
+    if username != "" {
+        m.auth = smtp.PlainAuth("", username, password, host)
+    }
+
+    return m
+}
+
+// Send sends msg.
+func (m *SMTP) Send(msg Message) error {
+    from, err := mail.ParseAddress(m.sender)
+    if err != nil {
+        return err
+    }
+
+    to, err := mail.ParseAddress(msg.To)
+    if err != nil {
+        return err
+    }
+
+    b, err := format(m.sender, msg, time.Now())
+    if err != nil {
+        return err
+    }
+
+    return smtp.SendMail(m.addr, m.auth, from.Address, []string{to.Address}, b)
+}
+
+// Writer writes emails to an io.Writer instead of sending them, for use during development and
+// in tests.
+type Writer struct {
+    mu     sync.Mutex
+    w      io.Writer
+    sender string
+}
+
+// NewWriter returns a Mailer which writes emails from sender to w, each followed by a blank line.
+func NewWriter(w io.Writer, sender string) *Writer {
+    return &Writer{w: w, sender: sender}
+}
+
+// Send writes msg.
+func (m *Writer) Send(msg Message) error {
+    b, err := format(m.sender, msg, time.Now())
+    if err != nil {
+        return err
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    _, err = m.w.Write(append(b, "\r\n\r\n"...))
+
+    return err
+}
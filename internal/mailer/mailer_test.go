@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"bytes"
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+    date := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+    b, err := format("Snippetbox <no-reply@example.com>", Message{
+        To:      "alice@example.com",
+        Subject: "Grüße",
+        Body:    "Hello,\nworld",
+    }, date)
+
+    assert.NilError(t, err)
+    assert.Equal(t, string(b), "From: Snippetbox <no-reply@example.com>\r\n"+
+        "To: alice@example.com\r\n"+
+        "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n"+
+        "Date: Sun, 17 Mar 2024 10:15:00 +0000\r\n"+
+        "MIME-Version: 1.0\r\n"+
+        "Content-Type: text/plain; charset=utf-8\r\n"+
+        "Content-Transfer-Encoding: 8bit\r\n"+
+        "\r\n"+
+        "Hello,\r\nworld")
+}
+
+func TestFormatHeaderInjection(t *testing.T) {
+    for _, msg := range []Message{
+        {To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"},
+        {To: "alice@example.com", Subject: "Hi\nBcc: eve@example.com"},
+    } {
+        _, err := format("no-reply@example.com", msg, time.Now())
+        assert.Equal(t, err, ErrInvalidHeader)
+    }
+}
+
+func TestWriter(t *testing.T) {
+    var buf bytes.Buffer
+
+    m := NewWriter(&buf, "no-reply@example.com")
+
+    err := m.Send(Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"})
+    assert.NilError(t, err)
+    assert.StringContains(t, buf.String(), "To: alice@example.com\r\n")
+    assert.StringContains(t, buf.String(), "\r\n\r\nHello\r\n\r\n")
+}
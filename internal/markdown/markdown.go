@@ -0,0 +1,287 @@
+// Package markdown renders a safe subset of Markdown as HTML.
+//
+// Raw HTML in the source is never passed through: all text is escaped, and links are only
+// rendered for the http, https and mailto schemes and for relative URLs. Images are rendered as
+// links, because the Content-Security-Policy of the application blocks images from other origins.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+    headingRX     = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+    ruleRX        = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+    fenceRX       = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([\\w+-]*)")
+    bulletRX      = regexp.MustCompile(`^ {0,3}[-*+][ \t]+`)
+    orderedRX     = regexp.MustCompile(`^ {0,3}\d{1,9}[.)][ \t]+`)
+    quoteRX       = regexp.MustCompile(`^ {0,3}> ?`)
+    indentationRX = regexp.MustCompile(`^(?: {2,}|\t)`)
+)
+
+// HTML renders the Markdown src as HTML.
+func HTML(src string) template.HTML {
+    src = strings.ReplaceAll(src, "\r\n", "\n")
+
+    var sb strings.Builder
+    renderBlocks(&sb, strings.Split(src, "\n"), false)
+
+    return template.HTML(sb.String())
+}
+
+// renderBlocks writes the block elements of lines to sb. If tight is true, as it is for the items
+// of a list, paragraphs are written without <p> tags.
+func renderBlocks(sb *strings.Builder, lines []string, tight bool) {
+    var paragraph []string
+
+    flush := func() {
+        if len(paragraph) == 0 {
+            return
+        }
+
+        text := strings.TrimSpace(strings.Join(paragraph, "\n"))
+        if tight {
+            sb.WriteString(renderInline(text))
+        } else {
+            sb.WriteString("<p>" + renderInline(text) + "</p>\n")
+        }
+        paragraph = nil
+    }
+
+    for i := 0; i < len(lines); i++ {
+        line := lines[i]
+
+        switch {
+        case strings.TrimSpace(line) == "":
+            flush()
+
+        case fenceRX.MatchString(line):
+            flush()
+
+            m := fenceRX.FindStringSubmatch(line)
+            fence, info := m[1], m[2]
+
+            var code []string
+            for i++; i < len(lines); i++ {
+                if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+                    break
+                }
+                code = append(code, lines[i])
+            }
+
+            sb.WriteString("<pre><code")
+            if info != "" {
+                sb.WriteString(` class="language-` + html.EscapeString(info) + `"`)
+            }
+            sb.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+        case headingRX.MatchString(line):
+            flush()
+
+            m := headingRX.FindStringSubmatch(line)
+            tag := "h" + string(rune('0'+len(m[1])))
+            sb.WriteString("<" + tag + ">" + renderInline(m[2]) + "</" + tag + ">\n")
+
+        case ruleRX.MatchString(line):
+            flush()
+            sb.WriteString("<hr>\n")
+
+        case quoteRX.MatchString(line):
+            flush()
+
+            var quoted []string
+            for ; i < len(lines) && quoteRX.MatchString(lines[i]); i++ {
+                quoted = append(quoted, quoteRX.ReplaceAllString(lines[i], ""))
+            }
+            i--
+
+            sb.WriteString("<blockquote>\n")
+            renderBlocks(sb, quoted, false)
+            sb.WriteString("</blockquote>\n")
+
+        case bulletRX.MatchString(line) || orderedRX.MatchString(line):
+            flush()
+
+            marker, tag := bulletRX, "ul"
+            if !bulletRX.MatchString(line) {
+                marker, tag = orderedRX, "ol"
+            }
+
+            sb.WriteString("<" + tag + ">\n")
+
+            for i < len(lines) && marker.MatchString(lines[i]) {
+                // An item continues on the following lines which are indented.
+                item := []string{marker.ReplaceAllString(lines[i], "")}
+                for i++; i < len(lines) && indentationRX.MatchString(lines[i]); i++ {
+                    item = append(item, indentationRX.ReplaceAllString(lines[i], ""))
+                }
+
+                sb.WriteString("<li>")
+                renderBlocks(sb, item, true)
+                sb.WriteString("</li>\n")
+            }
+            i--
+
+            sb.WriteString("</" + tag + ">\n")
+
+        default:
+            paragraph = append(paragraph, line)
+        }
+    }
+
+    flush()
+}
+
+// renderInline renders the inline elements of text: code spans, emphasis, links and images. All
+// other text is escaped.
+func renderInline(text string) string {
+    var sb strings.Builder
+
+    for i := 0; i < len(text); {
+        c := text[i]
+
+        switch {
+        case c == '\\' && i+1 < len(text) && strings.IndexByte(punctuation, text[i+1]) >= 0:
+            sb.WriteString(html.EscapeString(text[i+1 : i+2]))
+            i += 2
+            continue
+
+        case c == '`':
+            n := runLength(text[i:], '`')
+            fence := text[i : i+n]
+            if end := strings.Index(text[i+n:], fence); end >= 0 {
+                code := strings.TrimSpace(text[i+n : i+n+end])
+                sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+                i += n + end + n
+                continue
+            }
+            sb.WriteString(fence)
+            i += n
+            continue
+
+        case c == '[' || (c == '!' && strings.HasPrefix(text[i:], "![")):
+            start := i
+            if c == '!' {
+                start++
+            }
+            if label, href, n, ok := parseLink(text[start:]); ok {
+                sb.WriteString(renderLink(label, href))
+                i = start + n
+                continue
+            }
+
+        case c == '*' || c == '_':
+            delim := text[i : i+1]
+            if strings.HasPrefix(text[i:], delim+delim) {
+                delim += delim
+            }
+
+            // An underscore inside a word, as in snake_case, doesn't start emphasis.
+            intraword := c == '_' && i > 0 && isWordByte(text[i-1])
+
+            inner := i + len(delim)
+            end := strings.Index(text[min(inner+1, len(text)):], delim)
+            if !intraword && end >= 0 && text[inner] != ' ' {
+                end += inner + 1
+                tag := "em"
+                if len(delim) == 2 {
+                    tag = "strong"
+                }
+                sb.WriteString("<" + tag + ">" + renderInline(text[inner:end]) + "</" + tag + ">")
+                i = end + len(delim)
+                continue
+            }
+        }
+
+        sb.WriteString(html.EscapeString(text[i : i+1]))
+        i++
+    }
+
+    return sb.String()
+}
+
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+func runLength(s string, c byte) int {
+    n := 0
+    for n < len(s) && s[n] == c {
+        n++
+    }
+
+    return n
+}
+
+func isWordByte(c byte) bool {
+    return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// parseLink parses a link of the form [label](href) at the start of s, and returns the number of
+// bytes it takes up.
+func parseLink(s string) (label, href string, n int, ok bool) {
+    depth := 0
+
+    for i := 0; i < len(s); i++ {
+        switch s[i] {
+        case '[':
+            depth++
+        case ']':
+            depth--
+            if depth > 0 {
+                continue
+            }
+
+            if !strings.HasPrefix(s[i+1:], "(") {
+                return "", "", 0, false
+            }
+
+            end := strings.IndexByte(s[i+2:], ')')
+            if end < 0 {
+                return "", "", 0, false
+            }
+
+            return s[1:i], strings.TrimSpace(s[i+2 : i+2+end]), i + 2 + end + 1, true
+        }
+    }
+
+    return "", "", 0, false
+}
+
+// renderLink renders a link with the given label. If href is not a safe URL, only the label is
+// rendered.
+func renderLink(label, href string) string {
+    text := renderInline(label)
+
+    if !SafeURL(href) {
+        return text
+    }
+
+    return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">` + text + `</a>`
+}
+
+// SafeURL reports whether rawURL can be used as the target of a link: it must be relative, or use
+// the http, https or mailto scheme.
+func SafeURL(rawURL string) bool {
+    // Browsers ignore whitespace and control characters in URLs, so "java\tscript:" is a
+    // javascript: URL. Reject them instead of trying to predict how they're interpreted.
+    for _, r := range rawURL {
+        if r <= ' ' || r == 0x7F {
+            return false
+        }
+    }
+
+    u, err := url.Parse(rawURL)
+    if err != nil {
+        return false
+    }
+
+    switch strings.ToLower(u.Scheme) {
+    case "", "http", "https", "mailto":
+        return true
+    default:
+        return false
+    }
+}
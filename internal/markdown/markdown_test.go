@@ -0,0 +1,155 @@
+package markdown
+
+import (
+	"snippetbox/internal/assert"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+    tests := []struct {
+        name string
+        src  string
+        want string
+    }{
+        {
+            name: "Paragraphs",
+            src:  "First line\nsecond line\r\n\r\nNext paragraph",
+            want: "<p>First line\nsecond line</p>\n<p>Next paragraph</p>\n",
+        },
+        {
+            name: "Headings",
+            src:  "# Title #\n### Section",
+            want: "<h1>Title</h1>\n<h3>Section</h3>\n",
+        },
+        {
+            name: "Emphasis and code",
+            src:  "**bold**, *italic*, _also_ and `a < b` but not snake_case_name",
+            want: "<p><strong>bold</strong>, <em>italic</em>, <em>also</em> and <code>a &lt; b</code> but not snake_case_name</p>\n",
+        },
+        {
+            name: "Links",
+            src:  "[Go](https://go.dev), [home](/) and [mail](mailto:a@example.com)",
+            want: `<p><a href="https://go.dev" rel="nofollow noopener">Go</a>, <a href="/" rel="nofollow noopener">home</a> and <a href="mailto:a@example.com" rel="nofollow noopener">mail</a></p>` + "\n",
+        },
+        {
+            name: "Lists",
+            src:  "- one\n- two\n  - nested\n\n1. first\n2. second",
+            want: "<ul>\n<li>one</li>\n<li>two<ul>\n<li>nested</li>\n</ul>\n</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+        },
+        {
+            name: "Blockquote and rule",
+            src:  "> quoted\n> text\n\n---",
+            want: "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n<hr>\n",
+        },
+        {
+            name: "Fenced code",
+            src:  "```go\nif a && b {\n}\n```\nafter",
+            want: "<pre><code class=\"language-go\">if a &amp;&amp; b {\n}</code></pre>\n<p>after</p>\n",
+        },
+        {
+            name: "Escaped punctuation",
+            src:  `\*not emphasis\*`,
+            want: "<p>*not emphasis*</p>\n",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            assert.Equal(t, string(HTML(tc.src)), tc.want)
+        })
+    }
+}
+
+func TestHTMLNeutralisesXSS(t *testing.T) {
+    tests := []struct {
+        name string
+        src  string
+        want string
+    }{
+        {
+            name: "Script tag",
+            src:  "<script>alert(1)</script>",
+            want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+        },
+        {
+            name: "Event handler",
+            src:  `<img src=x onerror="alert(1)">`,
+            want: "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n",
+        },
+        {
+            name: "HTML in heading",
+            src:  "# <iframe src=//evil.example>",
+            want: "<h1>&lt;iframe src=//evil.example&gt;</h1>\n",
+        },
+        {
+            name: "HTML in code block",
+            src:  "```\n</code></pre><script>alert(1)</script>\n```",
+            want: "<pre><code>&lt;/code&gt;&lt;/pre&gt;&lt;script&gt;alert(1)&lt;/script&gt;</code></pre>\n",
+        },
+        {
+            name: "Code block language",
+            src:  "```\"><script>\nx\n```",
+            want: "<pre><code>x</code></pre>\n",
+        },
+        {
+            name: "JavaScript link",
+            src:  "[click](javascript:alert(1))",
+            want: "<p>click)</p>\n",
+        },
+        {
+            name: "Mixed case scheme",
+            src:  "[click](JaVaScRiPt:alert`1`)",
+            want: "<p>click</p>\n",
+        },
+        {
+            name: "Scheme split by a tab",
+            src:  "[click](java\tscript:alert`1`)",
+            want: "<p>click</p>\n",
+        },
+        {
+            name: "Encoded scheme",
+            src:  "[click](javascript&#58;alert`1`)",
+            want: `<p><a href="javascript&amp;#58;alert`+"`1`"+`" rel="nofollow noopener">click</a></p>` + "\n",
+        },
+        {
+            name: "Data URL",
+            src:  "[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+            want: "<p>click</p>\n",
+        },
+        {
+            name: "VBScript link",
+            src:  "[click](vbscript:msgbox)",
+            want: "<p>click</p>\n",
+        },
+        {
+            name: "Attribute breakout",
+            src:  `[click](https://example.com/"onmouseover="alert)`,
+            want: `<p><a href="https://example.com/&#34;onmouseover=&#34;alert" rel="nofollow noopener">click</a></p>` + "\n",
+        },
+        {
+            name: "JavaScript image",
+            src:  "![x](javascript:alert`1`)",
+            want: "<p>x</p>\n",
+        },
+        {
+            name: "HTML in link label",
+            src:  "[<b onclick=alert(1)>x</b>](/)",
+            want: `<p><a href="/" rel="nofollow noopener">&lt;b onclick=alert(1)&gt;x&lt;/b&gt;</a></p>` + "\n",
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            got := string(HTML(tc.src))
+
+            assert.Equal(t, got, tc.want)
+
+            for _, bad := range []string{"<script", "<img", "<iframe", "javascript:", "vbscript:", "data:"} {
+                if strings.Contains(strings.ToLower(got), bad) {
+                    t.Errorf("output contains %q: %s", bad, got)
+                }
+            }
+        })
+    }
+}
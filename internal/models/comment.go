@@ -0,0 +1,142 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Comment is the corresponding struct to database table comment.
+type Comment struct {
+    ID        int
+    SnippetID int
+    UserID    int
+    UserName  string  // The name of the user who wrote the comment.
+    ParentID  int  // The ID of the comment this one replies to, or 0 if it isn't a reply.
+    Line      int  // The line of the snippet the comment is about, or 0 if it is about the whole snippet.
+    Content   string
+    Created   time.Time
+    Deleted   time.Time  // The time the comment was deleted, or the zero time if it wasn't.
+}
+
+// CommentModel wraps a sql.DB connection pool.
+type CommentModel struct {
+    DB *sql.DB
+}
+
+// Insert inserts a new record in database table comment and returns its ID. parentID and line are
+// 0 for a comment which doesn't reply to another one or isn't anchored to a line.
+func (m *CommentModel) Insert(snippetID, userID, parentID, line int, content string) (int, error) {
+    stmt := `INSERT INTO comment(snippet_id, user_id, parent_id, line, content, created)
+             VALUES(?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+
+    result, err := m.DB.Exec(stmt, snippetID, userID,
+        sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0},
+        sql.NullInt64{Int64: int64(line), Valid: line != 0},
+        content)
+    if err != nil {
+        return 0, err
+    }
+
+    id, err := result.LastInsertId()
+
+    return int(id), err
+}
+
+// commentSelect selects the columns scanned by scanComment.
+const commentSelect = `SELECT c.id, c.snippet_id, c.user_id, u.name, COALESCE(c.parent_id, 0),
+                              COALESCE(c.line, 0), c.content, c.created, c.deleted_at
+                         FROM comment c
+                         JOIN user u ON u.id = c.user_id`
+
+func scanComment(row rowScanner) (Comment, error) {
+    var (
+        c       Comment
+        deleted sql.NullTime
+    )
+
+    err := row.Scan(&c.ID, &c.SnippetID, &c.UserID, &c.UserName, &c.ParentID, &c.Line, &c.Content,
+        &c.Created, &deleted)
+
+    c.Deleted = deleted.Time
+
+    return c, err
+}
+
+// Get returns a specific comment based on its ID.
+func (m *CommentModel) Get(id int) (Comment, error) {
+    c, err := scanComment(m.DB.QueryRow(commentSelect+` WHERE c.id = ?`, id))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Comment{}, ErrNoRecord
+        }
+        return Comment{}, err
+    }
+
+    return c, nil
+}
+
+// BySnippet returns the comments on the snippet id, including deleted ones, oldest first.
+func (m *CommentModel) BySnippet(id int) (comments []Comment, err error) {
+    stmt := commentSelect + `
+              WHERE c.snippet_id = ?
+              ORDER BY c.created, c.id`
+
+    rows, err := m.DB.Query(stmt, id)
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        closeErr := rows.Close()
+        if err != nil {
+            if closeErr != nil {
+                log.Printf("failed to close rows: %v", closeErr)
+            }
+            return
+        }
+        err = closeErr
+    }()
+
+    for rows.Next() {
+        c, err := scanComment(rows)
+        if err != nil {
+            return nil, err
+        }
+
+        comments = append(comments, c)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return comments, nil
+}
+
+// Delete deletes the comment id if it was written by the user userID or is on a snippet owned by
+// them. It returns ErrNoRecord if there is no such comment. The content of the comment is erased,
+// but the record is kept so that the replies to the comment keep their place in the thread.
+func (m *CommentModel) Delete(id, userID int) error {
+    stmt := `UPDATE comment c
+               JOIN snippet s ON s.id = c.snippet_id
+                SET c.content = '', c.deleted_at = UTC_TIMESTAMP()
+              WHERE c.id = ?
+                AND c.deleted_at IS NULL
+                AND (c.user_id = ? OR s.user_id = ?)`
+
+    result, err := m.DB.Exec(stmt, id, userID, userID)
+    if err != nil {
+        return err
+    }
+
+    n, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return ErrNoRecord
+    }
+
+    return nil
+}
@@ -6,4 +6,6 @@ var (
     ErrNoRecord           = errors.New("models: no matching record found")
     ErrDuplicateEmail     = errors.New("models: duplicate email")
     ErrInvalidCredentials = errors.New("models: invalid credentials")
+    ErrInvalidCursor      = errors.New("models: invalid cursor")
+    ErrUnverifiedEmail    = errors.New("models: unverified email address")
 )
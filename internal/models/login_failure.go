@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// LoginFailureModel wraps a sql.DB connection pool. It counts the consecutive failed logins of
+// subjects, such as a client IP address or an account, and records until when further logins by
+// them are blocked. A subject's failures are forgotten after a day without any.
+type LoginFailureModel struct {
+    DB *sql.DB
+}
+
+// BlockedUntil returns the latest time until which logins by any of subjects are blocked, or the
+// zero time if none of them is blocked.
+func (m *LoginFailureModel) BlockedUntil(subjects ...string) (time.Time, error) {
+    if len(subjects) == 0 {
+        return time.Time{}, nil
+    }
+
+    stmt := `SELECT MAX(blocked_until)
+               FROM login_failure
+              WHERE subject IN (?` + strings.Repeat(", ?", len(subjects)-1) + `)
+                AND blocked_until > UTC_TIMESTAMP()`
+
+    args := make([]any, len(subjects))
+    for i, s := range subjects {
+        args[i] = s
+    }
+
+    var until sql.NullTime
+
+    err := m.DB.QueryRow(stmt, args...).Scan(&until)
+    if err != nil {
+        return time.Time{}, err
+    }
+
+    return until.Time, nil
+}
+
+// Fail records a failed login by subject and returns the number of consecutive failures.
+func (m *LoginFailureModel) Fail(subject string) (int, error) {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    // The count starts again if the last failure was more than a day ago. MySQL assigns the
+    // columns from left to right, so failures is computed from the old last_failure.
+    stmt := `INSERT INTO login_failure(subject, failures, last_failure)
+             VALUES(?, 1, UTC_TIMESTAMP())
+                 ON DUPLICATE KEY UPDATE
+                    failures = IF(last_failure < UTC_TIMESTAMP() - INTERVAL 1 DAY, 1, failures + 1),
+                    last_failure = UTC_TIMESTAMP()`
+
+    _, err = tx.Exec(stmt, subject)
+    if err != nil {
+        return 0, err
+    }
+
+    var failures int
+
+    err = tx.QueryRow(`SELECT failures FROM login_failure WHERE subject = ?`, subject).Scan(&failures)
+    if err != nil {
+        return 0, err
+    }
+
+    return failures, tx.Commit()
+}
+
+// Block blocks logins by subject until until.
+func (m *LoginFailureModel) Block(subject string, until time.Time) error {
+    stmt := `UPDATE login_failure
+                SET blocked_until = ?
+              WHERE subject = ?`
+
+    _, err := m.DB.Exec(stmt, until.UTC(), subject)
+
+    return err
+}
+
+// Reset forgets the failed logins by subject, such as after it has logged in successfully.
+func (m *LoginFailureModel) Reset(subject string) error {
+    _, err := m.DB.Exec(`DELETE FROM login_failure WHERE subject = ?`, subject)
+
+    return err
+}
+
+// DeleteExpired permanently deletes at most batchSize subjects whose failures have been
+// forgotten and which aren't blocked, and returns the number of subjects deleted.
+func (m *LoginFailureModel) DeleteExpired(batchSize int) (int, error) {
+    stmt := `DELETE FROM login_failure
+              WHERE last_failure < UTC_TIMESTAMP() - INTERVAL 1 DAY
+                AND (blocked_until IS NULL OR blocked_until <= UTC_TIMESTAMP())
+              LIMIT ?`
+
+    result, err := m.DB.Exec(stmt, batchSize)
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := result.RowsAffected()
+
+    return int(n), err
+}
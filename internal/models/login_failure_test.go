@@ -0,0 +1,47 @@
+package models
+
+import (
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestLoginFailureModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+    m := LoginFailureModel{db}
+
+    for i := 1; i <= 3; i++ {
+        failures, err := m.Fail("account:alice@example.com")
+        assert.NilError(t, err)
+        assert.Equal(t, failures, i)
+    }
+
+    until, err := m.BlockedUntil("ip:192.0.2.1", "account:alice@example.com")
+    assert.NilError(t, err)
+    assert.Equal(t, until.IsZero(), true)
+
+    blocked := time.Now().Add(time.Hour).Truncate(time.Second)
+    assert.NilError(t, m.Block("account:alice@example.com", blocked))
+
+    until, err = m.BlockedUntil("ip:192.0.2.1", "account:alice@example.com")
+    assert.NilError(t, err)
+    assert.Equal(t, until.Equal(blocked), true)
+
+    assert.NilError(t, m.Reset("account:alice@example.com"))
+
+    failures, err := m.Fail("account:alice@example.com")
+    assert.NilError(t, err)
+    assert.Equal(t, failures, 1)
+
+    // Failures from more than a day ago are forgotten.
+    _, err = db.Exec(`UPDATE login_failure SET last_failure = UTC_TIMESTAMP() - INTERVAL 2 DAY`)
+    assert.NilError(t, err)
+
+    n, err := m.DeleteExpired(10)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 1)
+}
@@ -0,0 +1,69 @@
+package mocks
+
+import (
+	"snippetbox/internal/models"
+	"time"
+)
+
+var mockComments = []models.Comment{
+    {
+        ID: 1,
+        SnippetID: 1,
+        UserID: 2,
+        UserName: "Bob",
+        Content: "Lovely imagery.",
+        Created: time.Now(),
+    },
+    {
+        ID: 2,
+        SnippetID: 1,
+        UserID: 1,
+        UserName: "Alice",
+        ParentID: 1,
+        Content: "Thank you!",
+        Created: time.Now(),
+    },
+    {
+        ID: 3,
+        SnippetID: 1,
+        UserID: 2,
+        UserName: "Bob",
+        Line: 1,
+        Content: "Why silent?",
+        Created: time.Now(),
+    },
+}
+
+type CommentModel struct{}
+
+func (m *CommentModel) Insert(snippetID, userID, parentID, line int, content string) (int, error) {
+    return 4, nil
+}
+
+func (m *CommentModel) Get(id int) (models.Comment, error) {
+    for _, c := range mockComments {
+        if c.ID == id {
+            return c, nil
+        }
+    }
+
+    return models.Comment{}, models.ErrNoRecord
+}
+
+func (m *CommentModel) BySnippet(id int) ([]models.Comment, error) {
+    switch id {
+    case 1:
+        return mockComments, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *CommentModel) Delete(id, userID int) error {
+    // Comments 1 and 3 were written by user 2, but they are on a snippet owned by user 1.
+    if id >= 1 && id <= len(mockComments) && (userID == 1 || mockComments[id-1].UserID == userID) {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
@@ -0,0 +1,40 @@
+package mocks
+
+import "time"
+
+type LoginFailureModel struct{}
+
+func (m *LoginFailureModel) BlockedUntil(subjects ...string) (time.Time, error) {
+    for _, s := range subjects {
+        if s == "account:locked@example.com" {
+            return time.Now().Add(10 * time.Minute), nil
+        }
+    }
+
+    return time.Time{}, nil
+}
+
+// Fail reports that Alice's account has reached the number of failures which locks it out, and
+// that Bob's account has gone past it, as if a lockout had ended.
+func (m *LoginFailureModel) Fail(subject string) (int, error) {
+    switch subject {
+    case "account:alice@example.com":
+        return 10, nil
+    case "account:bob@example.com":
+        return 11, nil
+    default:
+        return 1, nil
+    }
+}
+
+func (m *LoginFailureModel) Block(subject string, until time.Time) error {
+    return nil
+}
+
+func (m *LoginFailureModel) Reset(subject string) error {
+    return nil
+}
+
+func (m *LoginFailureModel) DeleteExpired(batchSize int) (int, error) {
+    return 0, nil
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"snippetbox/internal/models"
+	"time"
+)
+
+// The token created for every password reset. It belongs to the user 1.
+const mockResetToken = "Rk3vN8qLw2XbT5yZc7HdJ0mPs4UfA9eGi6oVx1nQ2rE"
+
+type PasswordResetModel struct{}
+
+func (m *PasswordResetModel) Insert(userID int, ttl time.Duration) (string, error) {
+    return mockResetToken, nil
+}
+
+func (m *PasswordResetModel) Check(token string) (int, error) {
+    if token == mockResetToken {
+        return 1, nil
+    }
+
+    return 0, models.ErrNoRecord
+}
+
+func (m *PasswordResetModel) Reset(token, newPassword string) (int, error) {
+    if token == mockResetToken {
+        return 1, nil
+    }
+
+    return 0, models.ErrNoRecord
+}
+
+func (m *PasswordResetModel) DeleteExpired(batchSize int) (int, error) {
+    return 0, nil
+}
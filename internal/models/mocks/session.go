@@ -0,0 +1,91 @@
+package mocks
+
+import (
+	"snippetbox/internal/models"
+	"sync"
+	"time"
+)
+
+// SessionModel keeps the sessions it is given in memory, unlike the other mocks, so that the
+// sessions of the test server's clients can be listed and revoked.
+type SessionModel struct {
+    mu       sync.Mutex
+    sessions []models.Session
+}
+
+func (m *SessionModel) DeleteExpired(batchSize int) (int, error) {
+    return 0, nil
+}
+
+func (m *SessionModel) Insert(token string, userID int, userAgent, ip string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    now := time.Now()
+
+    m.sessions = append(m.sessions, models.Session{
+        ID:           len(m.sessions) + 1,
+        Token:        token,
+        UserID:       userID,
+        UserAgent:    userAgent,
+        IP:           ip,
+        Created:      now,
+        LastActivity: now,
+    })
+
+    return nil
+}
+
+func (m *SessionModel) Touch(token string) error {
+    return nil
+}
+
+func (m *SessionModel) ByUser(userID int) ([]models.Session, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    var sessions []models.Session
+
+    for _, s := range m.sessions {
+        if s.UserID == userID && s.Token != "" {
+            sessions = append(sessions, s)
+        }
+    }
+
+    return sessions, nil
+}
+
+// Revoke clears the token of a revoked session rather than removing it, so that IDs stay unique.
+func (m *SessionModel) Revoke(id, userID int) (string, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for i, s := range m.sessions {
+        if s.ID == id && s.UserID == userID && s.Token != "" {
+            m.sessions[i].Token = ""
+            return s.Token, nil
+        }
+    }
+
+    return "", models.ErrNoRecord
+}
+
+func (m *SessionModel) RevokeOthers(userID int, keep string) ([]string, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    var tokens []string
+
+    for i, s := range m.sessions {
+        if s.UserID == userID && s.Token != "" && s.Token != keep {
+            tokens = append(tokens, s.Token)
+            m.sessions[i].Token = ""
+        }
+    }
+
+    return tokens, nil
+}
+
+func (m *SessionModel) DeleteOrphans(batchSize int) (int, error) {
+    return 0, nil
+}
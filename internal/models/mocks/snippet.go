@@ -2,27 +2,183 @@ package mocks
 
 import (
 	"snippetbox/internal/models"
+	"strings"
 	"time"
 )
 
 var mockSnippet = models.Snippet{
     ID: 1,
+    UserID: 1,
     Title: "An old silent pond",
     Content: "An old silent pond...",
     Created: time.Now(),
     Expires: time.Now(),
+    Revision: 2,
+    Visibility: models.VisibilityPublic,
+    Slug: "pQ7nF2kLm0A",
+}
+
+var mockPrivateSnippet = models.Snippet{
+    ID: 4,
+    UserID: 1,
+    Title: "Private notes",
+    Content: "For my eyes only",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Visibility: models.VisibilityPrivate,
+    Slug: "Zr4-uW1cVbE",
+}
+
+var mockUnlistedSnippet = models.Snippet{
+    ID: 5,
+    UserID: 1,
+    Title: "First autumn morning",
+    Content: "First autumn morning...",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Visibility: models.VisibilityUnlisted,
+    Slug: "Xq3_aZ8rT0w",
+}
+
+var mockBurnSnippet = models.Snippet{
+    ID: 6,
+    UserID: 1,
+    Title: "Database password",
+    Content: "correct horse battery staple",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Visibility: models.VisibilityUnlisted,
+    Slug: "Bn6rE9wQx4M",
+    BurnAfterReading: true,
+}
+
+var mockProtectedSnippet = models.Snippet{
+    ID: 7,
+    UserID: 1,
+    Title: "Locked away",
+    Content: "Behind the locked door",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Visibility: models.VisibilityUnlisted,
+    Slug: "Pw5tG8yHj3K",
+    Protected: true,
+}
+
+var mockMarkdownSnippet = models.Snippet{
+    ID: 8,
+    UserID: 1,
+    Title: "Release notes",
+    Content: "# Release notes\n\n**Fixed** a bug <script>alert(1)</script> [reported here](javascript:alert(1)).",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Visibility: models.VisibilityPublic,
+    Slug: "Md8kT2vRw5N",
+    Language: "text",
+    Format: models.FormatMarkdown,
+    ForkedFrom: 1,
+}
+
+// mockUnlistedFork is a public fork of mockUnlistedSnippet by another user.
+var mockUnlistedFork = models.Snippet{
+    ID: 9,
+    UserID: 2,
+    Title: "First autumn morning, again",
+    Content: "First autumn morning...",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Visibility: models.VisibilityPublic,
+    Slug: "Fk2uL7nPq1Z",
+    ForkedFrom: 5,
+}
+
+var mockTrashedSnippet = models.Snippet{
+    ID: 3,
+    UserID: 1,
+    Title: "Over the wintry forest",
+    Content: "Over the wintry forest...",
+    Created: time.Now(),
+    Expires: time.Now(),
+    Revision: 1,
+    Deleted: time.Now(),
+    Visibility: models.VisibilityPublic,
+    Slug: "Lk8sD3_hYt2",
+}
+
+var mockRevisions = []models.Revision{
+    {
+        SnippetID: 1,
+        Number: 2,
+        Title: "An old silent pond",
+        Content: "An old silent pond...",
+        Created: time.Now(),
+    },
+    {
+        SnippetID: 1,
+        Number: 1,
+        Title: "An old pond",
+        Content: "An old pond...",
+        Created: time.Now(),
+    },
+}
+
+var mockFiles = []models.File{
+    {
+        SnippetID: 1,
+        Position: 1,
+        Name: "frog.txt",
+        Language: "text",
+        Content: "A frog jumps into the pond,",
+    },
+    {
+        SnippetID: 1,
+        Position: 2,
+        Name: "splash.sh",
+        Language: "shell",
+        Content: "echo 'splash! Silence again.'",
+    },
 }
 
 type SnippetModel struct{}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-    return 2, nil
+func (m *SnippetModel) Insert(s models.NewSnippet) (string, error) {
+    return "bT9vQ2xLd0E", nil
 }
 
 func (m *SnippetModel) Get(id int) (models.Snippet, error) {
     switch id {
     case 1:
         return mockSnippet, nil
+    case 4:
+        return mockPrivateSnippet, nil
+    case 5:
+        return mockUnlistedSnippet, nil
+    default:
+        return models.Snippet{}, models.ErrNoRecord
+    }
+}
+
+func (m *SnippetModel) GetBySlug(slug string) (models.Snippet, error) {
+    switch slug {
+    case mockSnippet.Slug:
+        return mockSnippet, nil
+    case mockPrivateSnippet.Slug:
+        return mockPrivateSnippet, nil
+    case mockUnlistedSnippet.Slug:
+        return mockUnlistedSnippet, nil
+    case mockBurnSnippet.Slug:
+        return mockBurnSnippet, nil
+    case mockProtectedSnippet.Slug:
+        return mockProtectedSnippet, nil
+    case mockMarkdownSnippet.Slug:
+        return mockMarkdownSnippet, nil
+    case mockUnlistedFork.Slug:
+        return mockUnlistedFork, nil
     default:
         return models.Snippet{}, models.ErrNoRecord
     }
@@ -30,4 +186,184 @@ func (m *SnippetModel) Get(id int) (models.Snippet, error) {
 
 func (m *SnippetModel) Latest(n int) ([]models.Snippet, error) {
     return []models.Snippet{mockSnippet}, nil
-}
\ No newline at end of file
+}
+
+func (m *SnippetModel) Archive(tag string, cursor models.Cursor, n int, newer bool) ([]models.Snippet, error) {
+    if (tag == "" || tag == "poetry") && cursor.IsZero() && !newer {
+        return []models.Snippet{mockSnippet}, nil
+    }
+
+    return nil, nil
+}
+
+func (m *SnippetModel) Search(query string, userID, limit, offset int) ([]models.Snippet, error) {
+    if strings.Contains(strings.ToLower(query), "pond") && offset == 0 {
+        return []models.Snippet{mockSnippet}, nil
+    }
+
+    return nil, nil
+}
+
+func (m *SnippetModel) ByUser(userID int) ([]models.Snippet, error) {
+    switch userID {
+    case 1:
+        return []models.Snippet{mockSnippet}, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) Update(id, userID int, title string, content string) error {
+    if id == 1 && userID == 1 {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
+
+func (m *SnippetModel) Revisions(id int) ([]models.Revision, error) {
+    switch id {
+    case 1:
+        return mockRevisions, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) Revision(id, n int) (models.Revision, error) {
+    if id == 1 {
+        for _, r := range mockRevisions {
+            if r.Number == n {
+                return r, nil
+            }
+        }
+    }
+
+    return models.Revision{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Delete(id, userID int) error {
+    if id == 1 && userID == 1 {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
+
+func (m *SnippetModel) Restore(id, userID int) error {
+    if id == 3 && userID == 1 {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
+
+func (m *SnippetModel) Purge(id, userID int) error {
+    if id == 3 && userID == 1 {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
+
+func (m *SnippetModel) Trash(userID int) ([]models.Snippet, error) {
+    switch userID {
+    case 1:
+        return []models.Snippet{mockTrashedSnippet}, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) DeleteExpired(batchSize int) (int, error) {
+    return 0, nil
+}
+
+func (m *SnippetModel) Burn(id int) (models.Snippet, error) {
+    switch id {
+    case 6:
+        return mockBurnSnippet, nil
+    default:
+        return models.Snippet{}, models.ErrNoRecord
+    }
+}
+
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+    switch {
+    case id != 7:
+        return models.ErrNoRecord
+    case passphrase != "open sesame":
+        return models.ErrInvalidCredentials
+    default:
+        return nil
+    }
+}
+
+func (m *SnippetModel) Files(id int) ([]models.File, error) {
+    switch id {
+    case 1:
+        return mockFiles, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) File(id, n int) (models.File, error) {
+    if id == 1 && n >= 1 && n <= len(mockFiles) {
+        return mockFiles[n-1], nil
+    }
+
+    return models.File{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Star(id, userID int) error {
+    return nil
+}
+
+func (m *SnippetModel) Unstar(id, userID int) error {
+    return nil
+}
+
+func (m *SnippetModel) Stars(id, userID int) (int, bool, error) {
+    switch id {
+    case 1:
+        return 2, userID == 1, nil
+    default:
+        return 0, false, nil
+    }
+}
+
+func (m *SnippetModel) Starred(userID int) ([]models.Snippet, error) {
+    switch userID {
+    case 1:
+        return []models.Snippet{mockMarkdownSnippet}, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) MostStarred(since time.Time, n int) ([]models.Snippet, error) {
+    return []models.Snippet{mockSnippet}, nil
+}
+
+func (m *SnippetModel) ForkCount(id int) (int, error) {
+    switch id {
+    case 1:
+        return 1, nil
+    default:
+        return 0, nil
+    }
+}
+
+func (m *SnippetModel) Tags(id int) ([]string, error) {
+    switch id {
+    case 1:
+        return []string{"haiku", "poetry"}, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) PopularTags(n int) ([]models.Tag, error) {
+    return []models.Tag{{Name: "haiku", Count: 1}, {Name: "poetry", Count: 3}}, nil
+}
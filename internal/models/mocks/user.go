@@ -12,14 +12,37 @@ var mockUser = models.User{
     Name: "Alice",
     Email: "alice@example.com",
     Created: time.Now(),
+    Verified: true,
 }
 
-func (m *UserModel) Insert(name, email, password string) error {
+var mockUnverifiedUser = models.User{
+    ID: 2,
+    Name: "Bob",
+    Email: "bob@example.com",
+    Created: time.Now(),
+}
+
+var mockTOTPUser = models.User{
+    ID: 3,
+    Name: "Carol",
+    Email: "carol@example.com",
+    Created: time.Now(),
+    Verified: true,
+    TOTPEnabled: true,
+}
+
+// The one-time password and recovery code accepted for mockTOTPUser.
+const (
+    mockTOTPCode     = "123456"
+    mockRecoveryCode = "abcd-efgh-ijkl-mnop"
+)
+
+func (m *UserModel) Insert(name, email, password string) (int, error) {
     switch email {
     case "dupe@example.com":
-        return models.ErrDuplicateEmail
+        return 0, models.ErrDuplicateEmail
     default:
-        return nil
+        return 2, nil
     }
 }
 
@@ -27,13 +50,27 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
     if email == "alice@example.com" && password == "pa$$word" {
         return 1, nil
     }
+    if email == "bob@example.com" && password == "pa$$word" {
+        return 2, models.ErrUnverifiedEmail
+    }
+    if email == "carol@example.com" && password == "pa$$word" {
+        return 3, nil
+    }
 
     return 0, models.ErrInvalidCredentials
 }
 
+func (m *UserModel) Verify(id int, email string) error {
+    if id == 2 && email == "bob@example.com" {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
+
 func (m *UserModel) Exists(id int) (bool, error) {
     switch id {
-    case 1:
+    case 1, 2, 3:
         return true, nil
     default:
         return false, nil
@@ -44,6 +81,23 @@ func (m *UserModel) Get(id int) (models.User, error) {
     switch id {
     case 1:
         return mockUser, nil
+    case 2:
+        return mockUnverifiedUser, nil
+    case 3:
+        return mockTOTPUser, nil
+    default:
+        return models.User{}, models.ErrNoRecord
+    }
+}
+
+func (m *UserModel) GetByEmail(email string) (models.User, error) {
+    switch email {
+    case mockUser.Email:
+        return mockUser, nil
+    case mockUnverifiedUser.Email:
+        return mockUnverifiedUser, nil
+    case mockTOTPUser.Email:
+        return mockTOTPUser, nil
     default:
         return models.User{}, models.ErrNoRecord
     }
@@ -59,4 +113,31 @@ func (m *UserModel) UpdatePassword(id int, currentPassword, newPassword string)
     }
 
     return models.ErrNoRecord
-}
\ No newline at end of file
+}
+func (m *UserModel) EnableTOTP(id int, secret string) ([]string, error) {
+    if id == 1 {
+        return []string{mockRecoveryCode, "qrst-uvwx-yz23-4567"}, nil
+    }
+
+    return nil, models.ErrNoRecord
+}
+
+func (m *UserModel) DisableTOTP(id int) error {
+    return nil
+}
+
+func (m *UserModel) CheckTOTP(id int, code string, now time.Time) error {
+    if id == 3 && code == mockTOTPCode {
+        return nil
+    }
+
+    return models.ErrInvalidCredentials
+}
+
+func (m *UserModel) UseRecoveryCode(id int, code string) error {
+    if id == 3 && code == mockRecoveryCode {
+        return nil
+    }
+
+    return models.ErrInvalidCredentials
+}
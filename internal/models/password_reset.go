@@ -0,0 +1,141 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordResetModel wraps a sql.DB connection pool. It manages the single-use tokens which let
+// users who have forgotten their password set a new one. Only the SHA-256 hash of a token is
+// stored, so the tokens can't be recovered from the database.
+type PasswordResetModel struct {
+    DB *sql.DB
+}
+
+// hashToken returns the hex encoded SHA-256 hash of token. Tokens are random, so a fast unsalted
+// hash is enough.
+func hashToken(token string) string {
+    h := sha256.Sum256([]byte(token))
+
+    return hex.EncodeToString(h[:])
+}
+
+// Insert creates a password reset token for the user userID which expires after ttl, and returns
+// the token.
+func (m *PasswordResetModel) Insert(userID int, ttl time.Duration) (string, error) {
+    b := make([]byte, 32)
+
+    _, err := rand.Read(b)
+    if err != nil {
+        return "", err
+    }
+
+    token := base64.RawURLEncoding.EncodeToString(b)
+
+    stmt := `INSERT INTO password_reset_token(hash, user_id, created, expires)
+             VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+
+    _, err = m.DB.Exec(stmt, hashToken(token), userID, int(ttl.Seconds()))
+    if err != nil {
+        return "", err
+    }
+
+    return token, nil
+}
+
+// Check returns the ID of the user for whom token was created. It returns ErrNoRecord if the
+// token doesn't exist, has been used or has expired.
+func (m *PasswordResetModel) Check(token string) (int, error) {
+    stmt := `SELECT user_id
+               FROM password_reset_token
+              WHERE hash = ?
+                AND expires > UTC_TIMESTAMP()`
+
+    var userID int
+
+    err := m.DB.QueryRow(stmt, hashToken(token)).Scan(&userID)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return 0, ErrNoRecord
+        } else {
+            return 0, err
+        }
+    }
+
+    return userID, nil
+}
+
+// Reset sets the password of the user for whom token was created to newPassword and returns the
+// user's ID. The token and any other tokens of the user are deleted. As following the link in the
+// email proves that the user owns their email address, it is marked as verified too. Reset
+// returns ErrNoRecord if the token isn't valid.
+func (m *PasswordResetModel) Reset(token, newPassword string) (int, error) {
+    newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+    if err != nil {
+        return 0, err
+    }
+
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    // Lock the token so that concurrent requests can't both use it.
+    stmt := `SELECT user_id
+               FROM password_reset_token
+              WHERE hash = ?
+                AND expires > UTC_TIMESTAMP()
+                FOR UPDATE`
+
+    var userID int
+
+    err = tx.QueryRow(stmt, hashToken(token)).Scan(&userID)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return 0, ErrNoRecord
+        } else {
+            return 0, err
+        }
+    }
+
+    _, err = tx.Exec(`DELETE FROM password_reset_token WHERE user_id = ?`, userID)
+    if err != nil {
+        return 0, err
+    }
+
+    stmt = `UPDATE user
+               SET hashed_password = ?, verified = TRUE
+             WHERE id = ?`
+
+    _, err = tx.Exec(stmt, string(newHashedPassword), userID)
+    if err != nil {
+        return 0, err
+    }
+
+    return userID, tx.Commit()
+}
+
+// DeleteExpired permanently deletes at most batchSize expired tokens and returns the number of
+// tokens deleted.
+func (m *PasswordResetModel) DeleteExpired(batchSize int) (int, error) {
+    stmt := `DELETE FROM password_reset_token
+              WHERE expires <= UTC_TIMESTAMP()
+              LIMIT ?`
+
+    result, err := m.DB.Exec(stmt, batchSize)
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := result.RowsAffected()
+
+    return int(n), err
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+    m := PasswordResetModel{db}
+    users := UserModel{db}
+
+    token, err := m.Insert(1, time.Hour)
+    assert.NilError(t, err)
+
+    // Only the hash of the token is stored.
+    var stored string
+    err = db.QueryRow(`SELECT hash FROM password_reset_token`).Scan(&stored)
+    assert.NilError(t, err)
+    assert.Equal(t, stored, hashToken(token))
+
+    userID, err := m.Check(token)
+    assert.NilError(t, err)
+    assert.Equal(t, userID, 1)
+
+    userID, err = m.Reset(token, "n3wPa$$word")
+    assert.NilError(t, err)
+    assert.Equal(t, userID, 1)
+
+    id, err := users.Authenticate("alice@example.com", "n3wPa$$word")
+    assert.NilError(t, err)
+    assert.Equal(t, id, 1)
+
+    // Tokens can only be used once.
+    _, err = m.Reset(token, "an0therPa$$word")
+    assert.Equal(t, err, ErrNoRecord)
+
+    expired, err := m.Insert(1, -time.Minute)
+    assert.NilError(t, err)
+
+    _, err = m.Check(expired)
+    assert.Equal(t, err, ErrNoRecord)
+
+    n, err := m.DeleteExpired(10)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 1)
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Session is a login session of a user. It is the corresponding struct to database table
+// user_session, which indexes the sessions of the scs session store by user.
+type Session struct {
+    ID           int
+    Token        string  // The token of the session in the scs session store.
+    UserID       int
+    UserAgent    string
+    IP           string
+    Created      time.Time
+    LastActivity time.Time
+}
+
+// SessionModel wraps a sql.DB connection pool. It gives access to database table sessions which
+// is otherwise managed by the scs session store, and to table user_session.
+type SessionModel struct {
+    DB *sql.DB
+}
+
+// DeleteExpired permanently deletes at most batchSize expired sessions and returns the number of
+// sessions deleted.
+func (m *SessionModel) DeleteExpired(batchSize int) (int, error) {
+    stmt := `DELETE FROM sessions
+              WHERE expiry < UTC_TIMESTAMP(6)
+              LIMIT ?`
+
+    result, err := m.DB.Exec(stmt, batchSize)
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := result.RowsAffected()
+
+    return int(n), err
+}
+
+// Insert records that the user userID has logged in with the session token. userAgent and ip
+// describe the client which logged in.
+func (m *SessionModel) Insert(token string, userID int, userAgent, ip string) error {
+    stmt := `INSERT INTO user_session(token, user_id, user_agent, ip, created, last_activity)
+             VALUES(?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP())`
+
+    // Truncate the user agent to the size of the column.
+    runes := []rune(userAgent)
+    if len(runes) > 255 {
+        userAgent = string(runes[:255])
+    }
+
+    _, err := m.DB.Exec(stmt, token, userID, userAgent, ip)
+
+    return err
+}
+
+// Touch records activity in the session token. To save writes, the time of the last activity is
+// only updated once a minute.
+func (m *SessionModel) Touch(token string) error {
+    stmt := `UPDATE user_session
+                SET last_activity = UTC_TIMESTAMP()
+              WHERE token = ?
+                AND last_activity < UTC_TIMESTAMP() - INTERVAL 1 MINUTE`
+
+    _, err := m.DB.Exec(stmt, token)
+
+    return err
+}
+
+// ByUser returns the sessions of the user userID which haven't ended, most recently active first.
+func (m *SessionModel) ByUser(userID int) (sessions []Session, err error) {
+    // Sessions which have been logged out or have expired are no longer in table sessions.
+    stmt := `SELECT us.id, us.token, us.user_id, us.user_agent, us.ip, us.created, us.last_activity
+               FROM user_session us
+                    JOIN sessions s ON s.token = us.token
+              WHERE us.user_id = ?
+                AND s.expiry > UTC_TIMESTAMP(6)
+              ORDER BY us.last_activity DESC, us.id DESC`
+
+    rows, err := m.DB.Query(stmt, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        closeErr := rows.Close()
+        if err != nil {
+            if closeErr != nil {
+                log.Printf("failed to close rows: %v", closeErr)
+            }
+            return
+        }
+        err = closeErr
+    }()
+
+    for rows.Next() {
+        var s Session
+
+        err := rows.Scan(&s.ID, &s.Token, &s.UserID, &s.UserAgent, &s.IP, &s.Created, &s.LastActivity)
+        if err != nil {
+            return nil, err
+        }
+
+        sessions = append(sessions, s)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return sessions, nil
+}
+
+// Revoke removes the session id of the user userID from the index and returns its token, which
+// the caller must delete from the session store. It returns ErrNoRecord if the user has no such
+// session.
+func (m *SessionModel) Revoke(id, userID int) (string, error) {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return "", err
+    }
+    defer tx.Rollback()
+
+    var token string
+
+    err = tx.QueryRow(`SELECT token FROM user_session WHERE id = ? AND user_id = ? FOR UPDATE`, id, userID).Scan(&token)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return "", ErrNoRecord
+        } else {
+            return "", err
+        }
+    }
+
+    _, err = tx.Exec(`DELETE FROM user_session WHERE id = ?`, id)
+    if err != nil {
+        return "", err
+    }
+
+    return token, tx.Commit()
+}
+
+// RevokeOthers removes all the sessions of the user userID except the session keep from the index
+// and returns their tokens, which the caller must delete from the session store.
+func (m *SessionModel) RevokeOthers(userID int, keep string) ([]string, error) {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    rows, err := tx.Query(`SELECT token FROM user_session WHERE user_id = ? AND token <> ? FOR UPDATE`, userID, keep)
+    if err != nil {
+        return nil, err
+    }
+
+    var tokens []string
+
+    for rows.Next() {
+        var token string
+
+        err = rows.Scan(&token)
+        if err != nil {
+            rows.Close()
+            return nil, err
+        }
+
+        tokens = append(tokens, token)
+    }
+
+    err = errors.Join(rows.Err(), rows.Close())
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = tx.Exec(`DELETE FROM user_session WHERE user_id = ? AND token <> ?`, userID, keep)
+    if err != nil {
+        return nil, err
+    }
+
+    return tokens, tx.Commit()
+}
+
+// DeleteOrphans permanently deletes at most batchSize rows of table user_session whose session has
+// ended, and returns the number of rows deleted. A session is only saved in the session store once
+// the request which created it has been handled, so rows younger than a minute are left alone.
+func (m *SessionModel) DeleteOrphans(batchSize int) (int, error) {
+    stmt := `DELETE FROM user_session
+              WHERE created < UTC_TIMESTAMP() - INTERVAL 1 MINUTE
+                AND token NOT IN (SELECT token FROM sessions WHERE expiry > UTC_TIMESTAMP(6))
+              LIMIT ?`
+
+    result, err := m.DB.Exec(stmt, batchSize)
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := result.RowsAffected()
+
+    return int(n), err
+}
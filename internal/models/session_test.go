@@ -0,0 +1,68 @@
+package models
+
+import (
+	"snippetbox/internal/assert"
+	"strings"
+	"testing"
+)
+
+func TestSessionModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+    m := SessionModel{db}
+
+    tokens := []string{strings.Repeat("a", 43), strings.Repeat("b", 43), strings.Repeat("c", 43)}
+    for _, token := range tokens {
+        _, err := db.Exec(`INSERT INTO sessions(token, data, expiry) VALUES(?, '', UTC_TIMESTAMP(6) + INTERVAL 1 HOUR)`, token)
+        assert.NilError(t, err)
+
+        assert.NilError(t, m.Insert(token, 1, "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", "192.0.2.1"))
+    }
+
+    // A session which has ended isn't listed.
+    _, err := db.Exec(`DELETE FROM sessions WHERE token = ?`, tokens[2])
+    assert.NilError(t, err)
+
+    sessions, err := m.ByUser(1)
+    assert.NilError(t, err)
+    assert.Equal(t, len(sessions), 2)
+    assert.Equal(t, sessions[0].Token, tokens[1])
+    assert.Equal(t, sessions[0].IP, "192.0.2.1")
+
+    assert.NilError(t, m.Touch(tokens[0]))
+
+    token, err := m.Revoke(sessions[0].ID, 2)
+    assert.Equal(t, err, ErrNoRecord)
+
+    token, err = m.Revoke(sessions[0].ID, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, token, tokens[1])
+
+    revoked, err := m.RevokeOthers(1, tokens[0])
+    assert.NilError(t, err)
+    assert.Equal(t, len(revoked), 1)
+    assert.Equal(t, revoked[0], tokens[2])
+
+    sessions, err = m.ByUser(1)
+    assert.NilError(t, err)
+    assert.Equal(t, len(sessions), 1)
+    assert.Equal(t, sessions[0].Token, tokens[0])
+
+    // The row of a session which has ended is deleted once it is old enough.
+    _, err = db.Exec(`DELETE FROM sessions WHERE token = ?`, tokens[0])
+    assert.NilError(t, err)
+
+    n, err := m.DeleteOrphans(10)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 0)
+
+    _, err = db.Exec(`UPDATE user_session SET created = UTC_TIMESTAMP() - INTERVAL 1 HOUR`)
+    assert.NilError(t, err)
+
+    n, err = m.DeleteOrphans(10)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 1)
+}
@@ -1,19 +1,159 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The visibilities of a snippet. Only public snippets are listed, unlisted snippets can be viewed
+// by anyone who knows their slug and private snippets can only be viewed by their owner.
+const (
+    VisibilityPublic   = "public"
+    VisibilityUnlisted = "unlisted"
+    VisibilityPrivate  = "private"
+)
+
+// The formats of a snippet. Plain text snippets are shown as highlighted code, Markdown snippets
+// are rendered as HTML.
+const (
+    FormatPlain    = "plain"
+    FormatMarkdown = "markdown"
 )
 
 // Snippet is the corresponding struct to database table snippet.
 type Snippet struct {
-    ID      int
-    Title   string
-    Content string
+    ID         int
+    UserID     int  // The ID of the user who created the snippet, or 0 if the owner is unknown.
+    Title      string
+    Content    string
+    Created    time.Time
+    Expires    time.Time  // The zero time if the snippet never expires.
+    Revision   int  // The number of the current revision. The original snippet is revision 1.
+    Deleted    time.Time  // The time the snippet was moved to the trash, or the zero time if it wasn't.
+    Visibility string
+    Slug       string  // A random, unguessable identifier which is used in the URL of the snippet.
+    BurnAfterReading bool  // Whether the snippet is deleted the first time it is revealed.
+    Protected        bool  // Whether a passphrase is needed to view the snippet.
+    Language         string  // The name of the language used to highlight the content.
+    Format           string  // How the content is rendered, plain text or Markdown.
+    ForkedFrom       int  // The ID of the snippet this one is a fork of, or 0 if it isn't a fork.
+}
+
+// OwnedBy reports whether the snippet was created by the user userID.
+func (s Snippet) OwnedBy(userID int) bool {
+    return userID != 0 && s.UserID == userID
+}
+
+// VisibleTo reports whether the user userID (0 for an anonymous user) may view the snippet.
+// bySlug reports whether the snippet was looked up by its slug rather than by its ID.
+func (s Snippet) VisibleTo(userID int, bySlug bool) bool {
+    switch s.Visibility {
+    case VisibilityPublic:
+        return true
+    case VisibilityUnlisted:
+        return bySlug || s.OwnedBy(userID)
+    default:
+        return s.OwnedBy(userID)
+    }
+}
+
+// URL returns the path of the page which shows the snippet.
+func (s Snippet) URL() string {
+    return "/s/" + s.Slug
+}
+
+// NewSnippet holds the fields supplied by a user when creating a snippet.
+type NewSnippet struct {
+    UserID           int
+    Title            string
+    Content          string
+    Visibility       string
+    BurnAfterReading bool
+    Passphrase       string  // If not empty, the passphrase needed to view the snippet.
+    Language         string
+    Format           string
+    Files            []File  // Further files shown after the content of the snippet.
+    Tags             []string
+    ForkedFrom       int  // The ID of the snippet which the new snippet is a fork of, if any.
+    Expires          time.Time  // When the snippet expires, or the zero time if it never expires.
+}
+
+// File is the corresponding struct to database table snippet_file. A snippet can hold several
+// files besides its own content, numbered by Position from 1.
+type File struct {
+    SnippetID int
+    Position  int
+    Name      string
+    Language  string
+    Content   string
+}
+
+// Tag is a tag together with the number of public snippets which have it.
+type Tag struct {
+    Name  string
+    Count int
+}
+
+// Revision is the corresponding struct to database table snippet_revision. The original content
+// of a snippet is stored in table snippet and is reported as revision 1.
+type Revision struct {
+    SnippetID int
+    Number    int
+    Title     string
+    Content   string
+    Created   time.Time
+}
+
+// Cursor is a position in the list of public snippets ordered from newest to oldest. The zero
+// Cursor is the start of the list.
+type Cursor struct {
     Created time.Time
-    Expires time.Time
+    ID      int
+}
+
+// CursorAt returns the position of the snippet s.
+func CursorAt(s Snippet) Cursor {
+    return Cursor{Created: s.Created, ID: s.ID}
+}
+
+// IsZero reports whether c is the start of the list.
+func (c Cursor) IsZero() bool {
+    return c.ID == 0
+}
+
+// String encodes c for use in a URL, as the Unix time of Created and the ID joined by a dash.
+func (c Cursor) String() string {
+    return strconv.FormatInt(c.Created.Unix(), 10) + "-" + strconv.Itoa(c.ID)
+}
+
+// ParseCursor decodes a cursor encoded by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+    created, id, ok := strings.Cut(s, "-")
+    if !ok {
+        return Cursor{}, ErrInvalidCursor
+    }
+
+    sec, err := strconv.ParseInt(created, 10, 64)
+    if err != nil || sec < 0 {
+        return Cursor{}, ErrInvalidCursor
+    }
+
+    n, err := strconv.Atoi(id)
+    if err != nil || n < 1 {
+        return Cursor{}, ErrInvalidCursor
+    }
+
+    return Cursor{Created: time.Unix(sec, 0).UTC(), ID: n}, nil
 }
 
 // SnippetModel wraps a sql.DB connection pool.
@@ -21,39 +161,216 @@ type SnippetModel struct {
     DB *sql.DB
 }
 
-// Insert inserts a new record in database table snippet.
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-    stmt := `INSERT INTO snippet(title, content, created, expires) 
-             VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+// snippetSelect selects the columns scanned by scanSnippet. The title and content come from the
+// current revision of the snippet if it has been edited, otherwise from the snippet itself.
+const snippetSelect = `SELECT s.id, COALESCE(s.user_id, 0), COALESCE(r.title, s.title),
+                              COALESCE(r.content, s.content), s.created, s.expires, s.revision,
+                              s.deleted_at, s.visibility, s.slug, s.burn_after_reading,
+                              s.hashed_passphrase IS NOT NULL, s.language, s.format,
+                              COALESCE(s.forked_from, 0)
+                         FROM snippet s
+                         LEFT JOIN snippet_revision r
+                           ON r.snippet_id = s.id
+                          AND r.revision = s.revision`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+    Scan(dest ...any) error
+}
+
+func scanSnippet(row rowScanner) (Snippet, error) {
+    var (
+        s       Snippet
+        expires sql.NullTime
+        deleted sql.NullTime
+    )
+
+    err := row.Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Created, &expires, &s.Revision, &deleted,
+        &s.Visibility, &s.Slug, &s.BurnAfterReading, &s.Protected,
+        &s.Language, &s.Format, &s.ForkedFrom)
 
-    result, err := m.DB.Exec(stmt, title, content, expires)
+    s.Expires = expires.Time
+    s.Deleted = deleted.Time
+
+    return s, err
+}
+
+// querySnippets runs a query which selects snippetSelect and returns the resulting snippets.
+func (m *SnippetModel) querySnippets(stmt string, args ...any) (snippets []Snippet, err error) {
+    rows, err := m.DB.Query(stmt, args...)
     if err != nil {
-        return 0, err
+        return nil, err
+    }
+    // We defer rows.Close() to ensure the sql.Rows resultset is always properly closed before this
+    // method returns. This defer statement should come *after* you check for an error from the
+    // Query() method. Otherwise, if Query() returns an error, you'll get a panic trying to close
+    // a nil resultset.
+    defer func() {
+        closeErr := rows.Close()
+        // If err was already not nil, we prioritize it.
+        if err != nil {
+            if closeErr != nil {
+                // Log the rows.Close() error.
+                log.Printf("failed to close rows: %v", closeErr)
+            }
+            return
+        }
+        err = closeErr
+    }()
+
+    // Use rows.Next to iterate through the rows in the resultset. This prepares the first (and
+    // then each subsequent) row to be acted on by the rows.Scan() method. If iteration over all
+    // the rows completes, the resultset automatically closes itself and freesup the uderlying
+    // database connection.
+    for rows.Next() {
+        s, err := scanSnippet(rows)
+        if err != nil {
+            return nil, err
+        }
+
+        snippets = append(snippets, s)
+    }
+
+    // When the rows.Next() loop has finished we call rows.Err() to retrieve any error that was
+    // encountered during the iteration. It's important to call this - don't assume that a
+    // successful iteration was completed over the whole resultset.
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return snippets, nil
+}
+
+// slugAttempts is the number of slugs tried by Insert before it gives up.
+const slugAttempts = 5
+
+// newSlug returns a random URL-safe slug of 11 characters.
+func newSlug() (string, error) {
+    b := make([]byte, 8)
+
+    _, err := rand.Read(b)
+    if err != nil {
+        return "", err
+    }
+
+    return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isDuplicateEntry reports whether err is a MySQL ER_DUP_ENTRY error for the unique constraint
+// named constraint.
+func isDuplicateEntry(err error, constraint string) bool {
+    var mySQLError *mysql.MySQLError
+
+    return errors.As(err, &mySQLError) && mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, constraint)
+}
+
+// Insert inserts a new record in database table snippet, its files in table snippet_file and its
+// tags in tables tag and snippet_tag. The snippet is given a random slug, which is returned.
+func (m *SnippetModel) Insert(s NewSnippet) (string, error) {
+    var hashedPassphrase []byte
+
+    if s.Passphrase != "" {
+        var err error
+
+        hashedPassphrase, err = bcrypt.GenerateFromPassword([]byte(s.Passphrase), 12)
+        if err != nil {
+            return "", err
+        }
+    }
+
+    stmt := `INSERT INTO snippet(user_id, title, content, visibility, slug, burn_after_reading,
+                                 hashed_passphrase, language, format, forked_from, created, expires)
+             VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), ?)`
+
+    // A NULL expiry time means the snippet never expires.
+    expires := sql.NullTime{Time: s.Expires.UTC(), Valid: !s.Expires.IsZero()}
+    forkedFrom := sql.NullInt64{Int64: int64(s.ForkedFrom), Valid: s.ForkedFrom != 0}
+
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return "", err
+    }
+    defer tx.Rollback()
+
+    var (
+        slug   string
+        result sql.Result
+    )
+
+    for attempt := 1; ; attempt++ {
+        slug, err = newSlug()
+        if err != nil {
+            return "", err
+        }
+
+        result, err = tx.Exec(stmt, s.UserID, s.Title, s.Content, s.Visibility, slug, s.BurnAfterReading,
+            hashedPassphrase, s.Language, s.Format, forkedFrom, expires)
+        if err == nil {
+            break
+        }
+
+        // In the unlikely event that the slug is already taken, try again with another one. A
+        // failed statement doesn't abort the transaction.
+        if !isDuplicateEntry(err, "uc_snippet_slug") || attempt == slugAttempts {
+            return "", err
+        }
     }
 
-    // Use the LastInsertId() method on the result to get the ID of our newly inserted record.
     id, err := result.LastInsertId()
     if err != nil {
-        return 0, err
+        return "", err
     }
 
-    // The ID returned has the type int64, so we convert it to an int type before returning.
-    return int(id), nil
+    stmt = `INSERT INTO snippet_file(snippet_id, position, name, language, content)
+            VALUES(?, ?, ?, ?, ?)`
+
+    for i, f := range s.Files {
+        _, err = tx.Exec(stmt, id, i+1, f.Name, f.Language, f.Content)
+        if err != nil {
+            return "", err
+        }
+    }
+
+    // A tag is created the first time it is used. Otherwise LAST_INSERT_ID(id) makes the ID of
+    // the existing tag the insert ID of the statement.
+    stmt = `INSERT INTO tag(name) VALUES(?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`
+
+    for _, name := range s.Tags {
+        result, err = tx.Exec(stmt, name)
+        if err != nil {
+            return "", err
+        }
+
+        tagID, err := result.LastInsertId()
+        if err != nil {
+            return "", err
+        }
+
+        _, err = tx.Exec(`INSERT INTO snippet_tag(snippet_id, tag_id) VALUES(?, ?)`, id, tagID)
+        if err != nil {
+            return "", err
+        }
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return "", err
+    }
+
+    return slug, nil
 }
 
-// Get returns a specific Snippet based on its ID.
+// Get returns a specific Snippet based on its ID. Trashed snippets are not returned.
 func (m *SnippetModel) Get(id int) (Snippet, error) {
-    stmt := `SELECT id, title, content, created, expires 
-               FROM snippet 
-              WHERE expires > UTC_TIMESTAMP() 
-                AND id = ?`
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.id = ?`
 
-    var s Snippet
-
-    err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+    s, err := scanSnippet(m.DB.QueryRow(stmt, id))
     if err != nil {
-        // If the query returns no rows, Scan() will return a sql.ErrNoRows error. We use the 
-        // errors.Is() function to check for that error specifically, and return our own 
+        // If the query returns no rows, Scan() will return a sql.ErrNoRows error. We use the
+        // errors.Is() function to check for that error specifically, and return our own
         // ErrNoRecord error instead.
         if errors.Is(err, sql.ErrNoRows) {
             return Snippet{}, ErrNoRecord
@@ -65,28 +382,436 @@ func (m *SnippetModel) Get(id int) (Snippet, error) {
     return s, nil
 }
 
-// Latest returns n most recently created snippets.
-func (m *SnippetModel) Latest(n int) (snippets []Snippet, err error) {
-    stmt := `SELECT id, title, content, created, expires 
-               FROM snippet 
-              WHERE expires > UTC_TIMESTAMP() 
-              ORDER BY id DESC 
+// GetBySlug returns a specific Snippet based on its slug. Trashed snippets are not returned.
+func (m *SnippetModel) GetBySlug(slug string) (Snippet, error) {
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.slug = ?`
+
+    s, err := scanSnippet(m.DB.QueryRow(stmt, slug))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Snippet{}, ErrNoRecord
+        } else {
+            return Snippet{}, err
+        }
+    }
+
+    return s, nil
+}
+
+// Latest returns n most recently created public snippets which are not in the trash.
+func (m *SnippetModel) Latest(n int) ([]Snippet, error) {
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.visibility = 'public'
+              ORDER BY s.id DESC
+              LIMIT ?`
+
+    return m.querySnippets(stmt, n)
+}
+
+// Archive returns up to n public snippets which come after cursor in the list of public snippets
+// ordered from newest to oldest. If newer is true, it returns the up to n snippets which come right
+// before cursor instead. Either way the snippets are ordered from newest to oldest. If tag is not
+// empty, only the snippets with that tag are listed.
+func (m *SnippetModel) Archive(tag string, cursor Cursor, n int, newer bool) ([]Snippet, error) {
+    // Snippets created in the same second are ordered by ID. The condition and the order can be
+    // served by idx_snippet_created, because InnoDB stores the primary key in every index.
+    cmp, order := "<", "DESC"
+    if newer {
+        cmp, order = ">", "ASC"
+    }
+
+    stmt := snippetSelect
+    var args []any
+
+    if tag != "" {
+        stmt += `
+                         JOIN snippet_tag st
+                           ON st.snippet_id = s.id
+                         JOIN tag t
+                           ON t.id = st.tag_id
+                          AND t.name = ?`
+        args = append(args, tag)
+    }
+
+    stmt += `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.visibility = 'public'`
+
+    if !cursor.IsZero() {
+        stmt += `
+                AND (s.created, s.id) ` + cmp + ` (?, ?)`
+        args = append(args, cursor.Created, cursor.ID)
+    }
+
+    stmt += `
+              ORDER BY s.created ` + order + `, s.id ` + order + `
+              LIMIT ?`
+    args = append(args, n)
+
+    snippets, err := m.querySnippets(stmt, args...)
+    if err != nil {
+        return nil, err
+    }
+
+    if newer {
+        slices.Reverse(snippets)
+    }
+
+    return snippets, nil
+}
+
+// Search returns up to limit snippets matching the words in query, most relevant first, after
+// skipping the first offset matches. Public snippets are searched unless their content can only be
+// seen after unlocking or burning them, as well as all the snippets of the user userID.
+func (m *SnippetModel) Search(query string, userID, limit, offset int) ([]Snippet, error) {
+    // The title and content of an edited snippet come from its current revision, so the original
+    // text in table snippet is only matched for snippets which haven't been edited.
+    const relevance = `IF(r.snippet_id IS NULL,
+                          MATCH(s.title, s.content) AGAINST (? IN NATURAL LANGUAGE MODE),
+                          MATCH(r.title, r.content) AGAINST (? IN NATURAL LANGUAGE MODE))`
+
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND (s.user_id = ?
+                     OR (s.visibility = 'public'
+                         AND s.hashed_passphrase IS NULL
+                         AND NOT s.burn_after_reading))
+                AND ` + relevance + ` > 0
+              ORDER BY ` + relevance + ` DESC, s.id DESC
+              LIMIT ? OFFSET ?`
+
+    return m.querySnippets(stmt, userID, query, query, query, query, limit, offset)
+}
+
+// ByUser returns all the unexpired snippets created by the user userID which are not in the
+// trash, most recent first.
+func (m *SnippetModel) ByUser(userID int) ([]Snippet, error) {
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.user_id = ?
+              ORDER BY s.id DESC`
+
+    return m.querySnippets(stmt, userID)
+}
+
+// Update saves a new revision of the snippet id owned by the user userID. The content of earlier
+// revisions is kept. It returns ErrNoRecord if no such unexpired snippet is owned by the user.
+func (m *SnippetModel) Update(id, userID int, title string, content string) error {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return err
+    }
+    // Rollback() is a no-op if the transaction has already been committed.
+    defer tx.Rollback()
+
+    stmt := `UPDATE snippet
+                SET revision = revision + 1
+              WHERE id = ?
+                AND user_id = ?
+                AND (expires IS NULL OR expires > UTC_TIMESTAMP())
+                AND deleted_at IS NULL`
+
+    result, err := tx.Exec(stmt, id, userID)
+    if err != nil {
+        return err
+    }
+
+    n, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return ErrNoRecord
+    }
+
+    stmt = `INSERT INTO snippet_revision(snippet_id, revision, title, content, created)
+            SELECT id, revision, ?, ?, UTC_TIMESTAMP()
+              FROM snippet
+             WHERE id = ?`
+
+    _, err = tx.Exec(stmt, title, content, id)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+// Revisions returns all the revisions of the snippet id, most recent first. The content of the
+// revisions is not loaded.
+func (m *SnippetModel) Revisions(id int) (revisions []Revision, err error) {
+    stmt := `SELECT id, 1, title, created
+               FROM snippet
+              WHERE (expires IS NULL OR expires > UTC_TIMESTAMP())
+                AND deleted_at IS NULL
+                AND id = ?
+              UNION ALL
+             SELECT r.snippet_id, r.revision, r.title, r.created
+               FROM snippet_revision r
+               JOIN snippet s ON s.id = r.snippet_id
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND r.snippet_id = ?
+              ORDER BY 2 DESC`
+
+    rows, err := m.DB.Query(stmt, id, id)
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        closeErr := rows.Close()
+        if err != nil {
+            if closeErr != nil {
+                log.Printf("failed to close rows: %v", closeErr)
+            }
+            return
+        }
+        err = closeErr
+    }()
+
+    for rows.Next() {
+        var r Revision
+
+        err = rows.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Created)
+        if err != nil {
+            return nil, err
+        }
+
+        revisions = append(revisions, r)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return revisions, nil
+}
+
+// Revision returns revision number n of the snippet id.
+func (m *SnippetModel) Revision(id, n int) (Revision, error) {
+    stmt := `SELECT id, 1, title, content, created
+               FROM snippet
+              WHERE (expires IS NULL OR expires > UTC_TIMESTAMP())
+                AND deleted_at IS NULL
+                AND id = ?
+                AND ? = 1
+              UNION ALL
+             SELECT r.snippet_id, r.revision, r.title, r.content, r.created
+               FROM snippet_revision r
+               JOIN snippet s ON s.id = r.snippet_id
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND r.snippet_id = ?
+                AND r.revision = ?`
+
+    var r Revision
+
+    err := m.DB.QueryRow(stmt, id, n, id, n).Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Created)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Revision{}, ErrNoRecord
+        } else {
+            return Revision{}, err
+        }
+    }
+
+    return r, nil
+}
+
+// Files returns the files of the snippet id in order of their position.
+func (m *SnippetModel) Files(id int) (files []File, err error) {
+    stmt := `SELECT f.snippet_id, f.position, f.name, f.language, f.content
+               FROM snippet_file f
+               JOIN snippet s ON s.id = f.snippet_id
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND f.snippet_id = ?
+              ORDER BY f.position`
+
+    rows, err := m.DB.Query(stmt, id)
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        closeErr := rows.Close()
+        if err != nil {
+            if closeErr != nil {
+                log.Printf("failed to close rows: %v", closeErr)
+            }
+            return
+        }
+        err = closeErr
+    }()
+
+    for rows.Next() {
+        var f File
+
+        err = rows.Scan(&f.SnippetID, &f.Position, &f.Name, &f.Language, &f.Content)
+        if err != nil {
+            return nil, err
+        }
+
+        files = append(files, f)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return files, nil
+}
+
+// Star records that the user userID has starred the snippet id. Starring a snippet twice has no
+// effect.
+func (m *SnippetModel) Star(id, userID int) error {
+    stmt := `INSERT INTO star(user_id, snippet_id, created)
+             VALUES(?, ?, UTC_TIMESTAMP())
+             ON DUPLICATE KEY UPDATE user_id = user_id`
+
+    _, err := m.DB.Exec(stmt, userID, id)
+
+    return err
+}
+
+// Unstar removes the star of the user userID from the snippet id, if there is one.
+func (m *SnippetModel) Unstar(id, userID int) error {
+    _, err := m.DB.Exec(`DELETE FROM star WHERE user_id = ? AND snippet_id = ?`, userID, id)
+
+    return err
+}
+
+// Stars returns the number of stars of the snippet id, and whether the user userID has starred it.
+func (m *SnippetModel) Stars(id, userID int) (count int, starred bool, err error) {
+    stmt := `SELECT COUNT(*), COALESCE(SUM(user_id = ?), 0) > 0
+               FROM star
+              WHERE snippet_id = ?`
+
+    err = m.DB.QueryRow(stmt, userID, id).Scan(&count, &starred)
+
+    return count, starred, err
+}
+
+// Starred returns the snippets starred by the user userID which they can still see, most recently
+// starred first. Unlisted snippets are included, as the user must have had the link to star them.
+func (m *SnippetModel) Starred(userID int) ([]Snippet, error) {
+    stmt := snippetSelect + `
+                         JOIN star st
+                           ON st.snippet_id = s.id
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND (s.visibility <> 'private' OR s.user_id = st.user_id)
+                AND st.user_id = ?
+              ORDER BY st.created DESC`
+
+    return m.querySnippets(stmt, userID)
+}
+
+// MostStarred returns up to n public snippets which have been starred the most times since the
+// given time, most starred first.
+func (m *SnippetModel) MostStarred(since time.Time, n int) ([]Snippet, error) {
+    stmt := snippetSelect + `
+                         JOIN (SELECT snippet_id, COUNT(*) AS stars
+                                 FROM star
+                                WHERE created >= ?
+                                GROUP BY snippet_id) st
+                           ON st.snippet_id = s.id
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.visibility = 'public'
+              ORDER BY st.stars DESC, s.id DESC
               LIMIT ?`
 
+    return m.querySnippets(stmt, since.UTC(), n)
+}
+
+// ForkCount returns the number of snippets which are forks of the snippet id, not counting
+// expired and trashed forks.
+func (m *SnippetModel) ForkCount(id int) (int, error) {
+    stmt := `SELECT COUNT(*)
+               FROM snippet
+              WHERE (expires IS NULL OR expires > UTC_TIMESTAMP())
+                AND deleted_at IS NULL
+                AND forked_from = ?`
+
+    var n int
+
+    err := m.DB.QueryRow(stmt, id).Scan(&n)
+
+    return n, err
+}
+
+// Tags returns the names of the tags of the snippet id in alphabetical order.
+func (m *SnippetModel) Tags(id int) (tags []string, err error) {
+    stmt := `SELECT t.name
+               FROM tag t
+               JOIN snippet_tag st ON st.tag_id = t.id
+              WHERE st.snippet_id = ?
+              ORDER BY t.name`
+
+    rows, err := m.DB.Query(stmt, id)
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        closeErr := rows.Close()
+        if err != nil {
+            if closeErr != nil {
+                log.Printf("failed to close rows: %v", closeErr)
+            }
+            return
+        }
+        err = closeErr
+    }()
+
+    for rows.Next() {
+        var name string
+
+        err = rows.Scan(&name)
+        if err != nil {
+            return nil, err
+        }
+
+        tags = append(tags, name)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return tags, nil
+}
+
+// PopularTags returns the n tags which the most public snippets have, in alphabetical order.
+func (m *SnippetModel) PopularTags(n int) (tags []Tag, err error) {
+    stmt := `SELECT name, count
+               FROM (SELECT t.name, COUNT(*) AS count
+                       FROM tag t
+                       JOIN snippet_tag st ON st.tag_id = t.id
+                       JOIN snippet s ON s.id = st.snippet_id
+                      WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                        AND s.deleted_at IS NULL
+                        AND s.visibility = 'public'
+                      GROUP BY t.id
+                      ORDER BY count DESC, t.name
+                      LIMIT ?) popular
+              ORDER BY name`
+
     rows, err := m.DB.Query(stmt, n)
     if err != nil {
         return nil, err
     }
-    // We defer rows.Close() to ensure the sql.Rows resultset is always properly closed before this
-    // method returns. This defer statement should come *after* you check for an error from the
-    // Query() method. Otherwise, if Query() returns an error, you'll get a panic trying to close 
-    // a nil resultset.
     defer func() {
         closeErr := rows.Close()
-        // If err was already not nil, we prioritize it.
         if err != nil {
             if closeErr != nil {
-                // Log the rows.Close() error.
                 log.Printf("failed to close rows: %v", closeErr)
             }
             return
@@ -94,27 +819,293 @@ func (m *SnippetModel) Latest(n int) (snippets []Snippet, err error) {
         err = closeErr
     }()
 
-    // Use rows.Next to iterate through the rows in the resultset. This prepares the first (and 
-    // then each subsequent) row to be acted on by the rows.Scan() method. If iteration over all 
-    // the rows completes, the resultset automatically closes itself and freesup the uderlying 
-    // database connection.
     for rows.Next() {
-        var s Snippet
+        var t Tag
 
-        err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+        err = rows.Scan(&t.Name, &t.Count)
         if err != nil {
             return nil, err
         }
 
-        snippets = append(snippets, s)
+        tags = append(tags, t)
     }
 
-    // When the rows.Next() loop has finished we call rows.Err() to retrieve any error that was 
-    // encountered during the iteration. It's important to call this - don't assume that a 
-    // successful iteration was completed over the whole resultset.
     if err = rows.Err(); err != nil {
         return nil, err
     }
 
-    return snippets, nil
-}
\ No newline at end of file
+    return tags, nil
+}
+
+// File returns the file of the snippet id at position n.
+func (m *SnippetModel) File(id, n int) (File, error) {
+    stmt := `SELECT f.snippet_id, f.position, f.name, f.language, f.content
+               FROM snippet_file f
+               JOIN snippet s ON s.id = f.snippet_id
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND f.snippet_id = ?
+                AND f.position = ?`
+
+    var f File
+
+    err := m.DB.QueryRow(stmt, id, n).Scan(&f.SnippetID, &f.Position, &f.Name, &f.Language, &f.Content)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return File{}, ErrNoRecord
+        } else {
+            return File{}, err
+        }
+    }
+
+    return f, nil
+}
+
+// execOwned executes stmt, which must affect a single snippet owned by a user, and returns
+// ErrNoRecord if no row was affected.
+func (m *SnippetModel) execOwned(stmt string, args ...any) error {
+    result, err := m.DB.Exec(stmt, args...)
+    if err != nil {
+        return err
+    }
+
+    n, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return ErrNoRecord
+    }
+
+    return nil
+}
+
+// Delete moves the snippet id owned by the user userID to the trash.
+func (m *SnippetModel) Delete(id, userID int) error {
+    stmt := `UPDATE snippet
+                SET deleted_at = UTC_TIMESTAMP()
+              WHERE id = ?
+                AND user_id = ?
+                AND deleted_at IS NULL`
+
+    return m.execOwned(stmt, id, userID)
+}
+
+// Restore moves the snippet id owned by the user userID out of the trash.
+func (m *SnippetModel) Restore(id, userID int) error {
+    stmt := `UPDATE snippet
+                SET deleted_at = NULL
+              WHERE id = ?
+                AND user_id = ?
+                AND deleted_at IS NOT NULL`
+
+    return m.execOwned(stmt, id, userID)
+}
+
+// Purge permanently deletes the snippet id owned by the user userID, together with its
+// revisions and comments. Only snippets in the trash can be purged.
+func (m *SnippetModel) Purge(id, userID int) error {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    stmt := `SELECT id
+               FROM snippet
+              WHERE id = ?
+                AND user_id = ?
+                AND deleted_at IS NOT NULL
+                FOR UPDATE`
+
+    err = tx.QueryRow(stmt, id, userID).Scan(&id)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrNoRecord
+        } else {
+            return err
+        }
+    }
+
+    err = deleteComments(tx, id)
+    if err != nil {
+        return err
+    }
+
+    _, err = tx.Exec(`DELETE FROM snippet WHERE id = ?`, id)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+// deleteComments deletes the comments on the snippets ids. They are deleted newest first: a reply
+// is always newer than the comment it replies to, so no delete cascades to replies. Leaving the
+// comments to the foreign keys would fail for threads deeper than the 15 levels of cascading
+// deletes InnoDB allows.
+func deleteComments(tx *sql.Tx, ids ...int) error {
+    stmt := `DELETE FROM comment
+              WHERE snippet_id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)
+              ORDER BY id DESC`
+
+    args := make([]any, len(ids))
+    for i, id := range ids {
+        args[i] = id
+    }
+
+    _, err := tx.Exec(stmt, args...)
+
+    return err
+}
+
+// Trash returns the unexpired snippets in the trash of the user userID, most recently deleted
+// first.
+func (m *SnippetModel) Trash(userID int) ([]Snippet, error) {
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NOT NULL
+                AND s.user_id = ?
+              ORDER BY s.deleted_at DESC`
+
+    return m.querySnippets(stmt, userID)
+}
+
+// DeleteExpired permanently deletes at most batchSize expired snippets, including those in the
+// trash, and returns the number of snippets deleted. Their revisions and comments are deleted with
+// them.
+func (m *SnippetModel) DeleteExpired(batchSize int) (int, error) {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    stmt := `SELECT id
+               FROM snippet
+              WHERE expires <= UTC_TIMESTAMP()
+              LIMIT ?
+                FOR UPDATE`
+
+    rows, err := tx.Query(stmt, batchSize)
+    if err != nil {
+        return 0, err
+    }
+
+    var ids []int
+
+    for rows.Next() {
+        var id int
+
+        err = rows.Scan(&id)
+        if err != nil {
+            rows.Close()
+            return 0, err
+        }
+
+        ids = append(ids, id)
+    }
+
+    err = errors.Join(rows.Err(), rows.Close())
+    if err != nil {
+        return 0, err
+    }
+
+    if len(ids) == 0 {
+        return 0, nil
+    }
+
+    err = deleteComments(tx, ids...)
+    if err != nil {
+        return 0, err
+    }
+
+    args := make([]any, len(ids))
+    for i, id := range ids {
+        args[i] = id
+    }
+
+    _, err = tx.Exec(`DELETE FROM snippet WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`, args...)
+    if err != nil {
+        return 0, err
+    }
+
+    return len(ids), tx.Commit()
+}
+
+// Burn returns the burn after reading snippet id and deletes it in the same transaction. The row
+// is locked while it is read, so if two users reveal the snippet at the same time only one of
+// them sees it and the other gets ErrNoRecord.
+func (m *SnippetModel) Burn(id int) (Snippet, error) {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return Snippet{}, err
+    }
+    defer tx.Rollback()
+
+    stmt := snippetSelect + `
+              WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+                AND s.deleted_at IS NULL
+                AND s.burn_after_reading
+                AND s.id = ?
+                FOR UPDATE`
+
+    s, err := scanSnippet(tx.QueryRow(stmt, id))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Snippet{}, ErrNoRecord
+        } else {
+            return Snippet{}, err
+        }
+    }
+
+    err = deleteComments(tx, id)
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    _, err = tx.Exec(`DELETE FROM snippet WHERE id = ?`, id)
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    return s, nil
+}
+
+// Unlock checks passphrase against the passphrase of the protected snippet id. It returns
+// ErrInvalidCredentials if they don't match.
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+    stmt := `SELECT hashed_passphrase
+               FROM snippet
+              WHERE (expires IS NULL OR expires > UTC_TIMESTAMP())
+                AND deleted_at IS NULL
+                AND hashed_passphrase IS NOT NULL
+                AND id = ?`
+
+    var hashedPassphrase []byte
+
+    err := m.DB.QueryRow(stmt, id).Scan(&hashedPassphrase)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrNoRecord
+        } else {
+            return err
+        }
+    }
+
+    err = bcrypt.CompareHashAndPassword(hashedPassphrase, []byte(passphrase))
+    if err != nil {
+        if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+            return ErrInvalidCredentials
+        } else {
+            return err
+        }
+    }
+
+    return nil
+}
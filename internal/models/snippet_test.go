@@ -0,0 +1,75 @@
+package models
+
+import (
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+    c := Cursor{Created: time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC), ID: 42}
+
+    assert.Equal(t, c.String(), "1710670500-42")
+
+    parsed, err := ParseCursor(c.String())
+    assert.NilError(t, err)
+    assert.Equal(t, parsed, c)
+
+    for _, s := range []string{"", "1710670500", "1710670500-0", "-1-42", "x-42", "1710670500-y"} {
+        _, err := ParseCursor(s)
+        assert.Equal(t, err, ErrInvalidCursor)
+    }
+}
+
+func TestSnippetModelDeleteDeepThread(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+    m := SnippetModel{db}
+    comments := CommentModel{db}
+
+    // insert creates a snippet of Alice with a thread of replies deeper than InnoDB cascades
+    // deletes.
+    insert := func(t *testing.T, slug string, deleted, expired bool) int {
+        stmt := `INSERT INTO snippet(user_id, title, content, created, expires, slug, deleted_at)
+                 VALUES(1, 'Thread', 'Content', UTC_TIMESTAMP(),
+                        IF(?, UTC_TIMESTAMP() - INTERVAL 1 DAY, NULL), ?, IF(?, UTC_TIMESTAMP(), NULL))`
+
+        result, err := db.Exec(stmt, expired, slug, deleted)
+        assert.NilError(t, err)
+
+        id, err := result.LastInsertId()
+        assert.NilError(t, err)
+
+        parentID := 0
+        for i := 0; i < 20; i++ {
+            parentID, err = comments.Insert(int(id), 1, parentID, 0, "Reply")
+            assert.NilError(t, err)
+        }
+
+        return int(id)
+    }
+
+    t.Run("Purge", func(t *testing.T) {
+        id := insert(t, "purgeThread", true, false)
+
+        assert.NilError(t, m.Purge(id, 1))
+        assert.Equal(t, m.Purge(id, 1), ErrNoRecord)
+    })
+
+    t.Run("DeleteExpired", func(t *testing.T) {
+        insert(t, "expiredThrea", false, true)
+
+        n, err := m.DeleteExpired(10)
+        assert.NilError(t, err)
+        assert.Equal(t, n, 1)
+    })
+
+    var count int
+
+    err := db.QueryRow(`SELECT COUNT(*) FROM comment`).Scan(&count)
+    assert.NilError(t, err)
+    assert.Equal(t, count, 0)
+}
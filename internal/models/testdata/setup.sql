@@ -1,28 +1,163 @@
-CREATE TABLE snippet (
-    id      INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
-    title   VARCHAR(100) NOT NULL,
-    content TEXT         NOT NULL,
-    created DATETIME     NOT NULL,
-    expires DATETIME     NOT NULL
-);
-
-CREATE INDEX idx_snippet_created ON snippet(created);
-
-
 CREATE TABLE user (
     id              INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
     name            VARCHAR(255) NOT NULL,
     email           VARCHAR(255) NOT NULL,
     hashed_password CHAR(60)     NOT NULL,
-    created         DATETIME     NOT NULL
+    created         DATETIME     NOT NULL,
+    verified        BOOLEAN      NOT NULL DEFAULT FALSE,
+    totp_secret     VARCHAR(32),
+    totp_step       BIGINT       NOT NULL DEFAULT 0
 );
 
 ALTER TABLE user ADD CONSTRAINT uc_user_email UNIQUE (email);
 
-INSERT INTO user (name, email, hashed_password, created) VALUES (
+
+CREATE TABLE snippet (
+    id         INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id    INTEGER,
+    title      VARCHAR(100) NOT NULL,
+    content    TEXT         NOT NULL,
+    created    DATETIME     NOT NULL,
+    expires    DATETIME,
+    revision   INTEGER      NOT NULL DEFAULT 1,
+    deleted_at DATETIME,
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+    slug       VARCHAR(16)  NOT NULL,
+    burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE,
+    hashed_passphrase  CHAR(60),
+    language   VARCHAR(20)  NOT NULL DEFAULT 'text',
+    format     ENUM('plain', 'markdown') NOT NULL DEFAULT 'plain',
+    forked_from INTEGER,
+    CONSTRAINT fk_snippet_user FOREIGN KEY (user_id) REFERENCES user(id),
+    CONSTRAINT fk_snippet_forked_from FOREIGN KEY (forked_from) REFERENCES snippet(id) ON DELETE SET NULL,
+    CONSTRAINT uc_snippet_slug UNIQUE (slug)
+);
+
+CREATE INDEX idx_snippet_created ON snippet(created);
+CREATE INDEX idx_snippet_user_id ON snippet(user_id);
+CREATE INDEX idx_snippet_expires ON snippet(expires);
+CREATE FULLTEXT INDEX ft_snippet_title_content ON snippet(title, content);
+
+CREATE TABLE snippet_revision (
+    snippet_id INTEGER      NOT NULL,
+    revision   INTEGER      NOT NULL,
+    title      VARCHAR(100) NOT NULL,
+    content    TEXT         NOT NULL,
+    created    DATETIME     NOT NULL,
+    PRIMARY KEY (snippet_id, revision),
+    CONSTRAINT fk_snippet_revision_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE
+);
+
+CREATE FULLTEXT INDEX ft_snippet_revision_title_content ON snippet_revision(title, content);
+
+CREATE TABLE snippet_file (
+    snippet_id INTEGER      NOT NULL,
+    position   INTEGER      NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    language   VARCHAR(20)  NOT NULL DEFAULT 'text',
+    content    TEXT         NOT NULL,
+    PRIMARY KEY (snippet_id, position),
+    CONSTRAINT fk_snippet_file_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE
+);
+
+CREATE TABLE tag (
+    id   INTEGER     NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(30) NOT NULL,
+    CONSTRAINT uc_tag_name UNIQUE (name)
+);
+
+CREATE TABLE snippet_tag (
+    snippet_id INTEGER NOT NULL,
+    tag_id     INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id),
+    CONSTRAINT fk_snippet_tag_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_snippet_tag_tag FOREIGN KEY (tag_id) REFERENCES tag(id)
+);
+
+CREATE INDEX idx_snippet_tag_tag_id ON snippet_tag(tag_id);
+
+CREATE TABLE star (
+    user_id    INTEGER  NOT NULL,
+    snippet_id INTEGER  NOT NULL,
+    created    DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snippet_id),
+    CONSTRAINT fk_star_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_star_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_star_snippet_id ON star(snippet_id);
+CREATE INDEX idx_star_created ON star(created);
+
+CREATE TABLE comment (
+    id         INTEGER  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER  NOT NULL,
+    user_id    INTEGER  NOT NULL,
+    parent_id  INTEGER,
+    line       INTEGER,
+    content    TEXT     NOT NULL,
+    created    DATETIME NOT NULL,
+    deleted_at DATETIME,
+    CONSTRAINT fk_comment_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_parent FOREIGN KEY (parent_id) REFERENCES comment(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_comment_snippet_id_created ON comment(snippet_id, created);
+
+CREATE TABLE password_reset_token (
+    hash    CHAR(64) NOT NULL PRIMARY KEY,
+    user_id INTEGER  NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    CONSTRAINT fk_password_reset_token_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_reset_token_expires ON password_reset_token(expires);
+
+CREATE TABLE recovery_code (
+    user_id INTEGER  NOT NULL,
+    hash    CHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, hash),
+    CONSTRAINT fk_recovery_code_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE login_failure (
+    subject       VARCHAR(320) NOT NULL PRIMARY KEY,
+    failures      INTEGER      NOT NULL,
+    last_failure  DATETIME     NOT NULL,
+    blocked_until DATETIME
+);
+
+CREATE INDEX idx_login_failure_last_failure ON login_failure(last_failure);
+
+CREATE TABLE sessions (
+    token  CHAR(43)     PRIMARY KEY,
+    data   BLOB         NOT NULL,
+    expiry TIMESTAMP(6) NOT NULL
+);
+
+CREATE INDEX idx_sessions_expiry ON sessions (expiry);
+
+CREATE TABLE user_session (
+    id            INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    token         CHAR(43)     NOT NULL,
+    user_id       INTEGER      NOT NULL,
+    user_agent    VARCHAR(255) NOT NULL,
+    ip            VARCHAR(45)  NOT NULL,
+    created       DATETIME     NOT NULL,
+    last_activity DATETIME     NOT NULL,
+    CONSTRAINT fk_user_session_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+ALTER TABLE user_session ADD CONSTRAINT uc_user_session_token UNIQUE (token);
+CREATE INDEX idx_user_session_user_id ON user_session(user_id);
+
+
+INSERT INTO user (name, email, hashed_password, created, verified) VALUES (
     'Alice Jones',
     'alice@example.com',
     '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
-    '2022-01-01 09:18:24'
+    '2022-01-01 09:18:24',
+    TRUE
 );
 COMMIT;
\ No newline at end of file
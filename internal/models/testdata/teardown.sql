@@ -1,3 +1,25 @@
-DROP TABLE user;
+DROP TABLE user_session;
 
-DROP TABLE snippet;
\ No newline at end of file
+DROP TABLE sessions;
+
+DROP TABLE login_failure;
+
+DROP TABLE recovery_code;
+
+DROP TABLE password_reset_token;
+
+DROP TABLE comment;
+
+DROP TABLE star;
+
+DROP TABLE snippet_tag;
+
+DROP TABLE tag;
+
+DROP TABLE snippet_file;
+
+DROP TABLE snippet_revision;
+
+DROP TABLE snippet;
+
+DROP TABLE user;
\ No newline at end of file
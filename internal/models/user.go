@@ -1,8 +1,11 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base32"
 	"errors"
+	"snippetbox/internal/totp"
 	"strings"
 	"time"
 
@@ -17,6 +20,8 @@ type User struct {
     Email          string
     HashedPassword string
     Created        time.Time
+    Verified       bool  // Whether the user has confirmed that they own their email address.
+    TOTPEnabled    bool  // Whether the user has to enter a one-time password when they log in.
 }
 
 // UserModel wraps a *sql.DB connection pool.
@@ -24,17 +29,18 @@ type UserModel struct {
     DB *sql.DB
 }
 
-// Insert inserts a record in the user table.
-func (m *UserModel) Insert(name, email, password string) error {
+// Insert inserts a record in the user table and returns its ID. The email address of the new user
+// is unverified.
+func (m *UserModel) Insert(name, email, password string) (int, error) {
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
     if err != nil {
-        return err
+        return 0, err
     }
 
     stmt := `INSERT INTO user(name, email, hashed_password, created) 
              VALUES (?, ?, ?, UTC_TIMESTAMP())`
 
-    _, err = m.DB.Exec(stmt, name, email, hashedPassword)
+    result, err := m.DB.Exec(stmt, name, email, hashedPassword)
     if err != nil {
         // If this returns an error, we use the errors.As() function to check whether the rror has
         // the type *mysql.MySQLError. If it does, the error will be assigned to the mySQLError
@@ -44,25 +50,47 @@ func (m *UserModel) Insert(name, email, password string) error {
         var mySQLError *mysql.MySQLError
         if errors.As(err, &mySQLError) {
             if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "uc_user_email") {
-                return ErrDuplicateEmail
+                return 0, ErrDuplicateEmail
             }
         }
 
-        return err
+        return 0, err
     }
 
-    return nil
+    id, err := result.LastInsertId()
+
+    return int(id), err
 }
 
 // Get returns a specific User based on its ID.
 func (m *UserModel) Get(id int) (User, error) {
-    stmt := `SELECT id, name, email, hashed_password, created 
+    stmt := `SELECT id, name, email, hashed_password, created, verified, totp_secret IS NOT NULL
                FROM user
               WHERE id = ?`
 
     var u User
 
-    err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.HashedPassword, &u.Created)
+    err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.HashedPassword, &u.Created, &u.Verified, &u.TOTPEnabled)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return User{}, ErrNoRecord
+        } else {
+            return User{}, err
+        }
+    }
+
+    return u, nil
+}
+
+// GetByEmail returns the User with the email address email.
+func (m *UserModel) GetByEmail(email string) (User, error) {
+    stmt := `SELECT id, name, email, hashed_password, created, verified, totp_secret IS NOT NULL
+               FROM user
+              WHERE email = ?`
+
+    var u User
+
+    err := m.DB.QueryRow(stmt, email).Scan(&u.ID, &u.Name, &u.Email, &u.HashedPassword, &u.Created, &u.Verified, &u.TOTPEnabled)
     if err != nil {
         if errors.Is(err, sql.ErrNoRows) {
             return User{}, ErrNoRecord
@@ -86,18 +114,20 @@ func (m *UserModel) Exists(id int) (bool, error) {
 }
 
 // Authenticate verifies whether a user exists based on the provided email and password.
-// It returns the relevant user ID if they do.
+// It returns the relevant user ID if they do. If the user hasn't verified their email address
+// yet, it returns their ID together with ErrUnverifiedEmail.
 func (m *UserModel) Authenticate(email, password string) (int, error) {
-    stmt := `SELECT id, hashed_password
+    stmt := `SELECT id, hashed_password, verified
                FROM user 
               WHERE email = ?`
 
     var (
         id             int
         hashedPassword string
+        verified       bool
     )
 
-    err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+    err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword, &verified)
     if err != nil {
         if errors.Is(err, sql.ErrNoRows) {
             return 0, ErrInvalidCredentials
@@ -115,9 +145,47 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
         }
     }
 
+    if !verified {
+        return id, ErrUnverifiedEmail
+    }
+
     return id, nil
 }
 
+// Verify marks the email address of the user id as verified, provided that it is still email. It
+// returns ErrNoRecord if there is no such user.
+func (m *UserModel) Verify(id int, email string) error {
+    stmt := `UPDATE user
+                SET verified = TRUE
+              WHERE id = ?
+                AND email = ?`
+
+    result, err := m.DB.Exec(stmt, id, email)
+    if err != nil {
+        return err
+    }
+
+    // A user who is already verified isn't changed, so match the row instead of counting the
+    // affected rows.
+    n, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        var exists bool
+
+        err = m.DB.QueryRow(`SELECT EXISTS(SELECT true FROM user WHERE id = ? AND email = ?)`, id, email).Scan(&exists)
+        if err != nil {
+            return err
+        }
+        if !exists {
+            return ErrNoRecord
+        }
+    }
+
+    return nil
+}
+
 // UpdatePassword updates a user's password.
 func (m *UserModel) UpdatePassword(id int, currentPassword, newPassword string) error {
     stmt := `SELECT hashed_password 
@@ -157,3 +225,160 @@ func (m *UserModel) UpdatePassword(id int, currentPassword, newPassword string)
 
     return err
 }
+
+// recoveryCodeCount is the number of recovery codes a user gets when they enable two-factor
+// authentication.
+const recoveryCodeCount = 10
+
+// newRecoveryCode returns a random recovery code of 80 bits, formatted like abcd-efgh-ijkl-mnop.
+func newRecoveryCode() (string, error) {
+    b := make([]byte, 10)
+
+    _, err := rand.Read(b)
+    if err != nil {
+        return "", err
+    }
+
+    code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+
+    return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// normalizeRecoveryCode removes the formatting from a recovery code entered by a user.
+func normalizeRecoveryCode(code string) string {
+    return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// EnableTOTP turns on two-factor authentication for the user id with the TOTP secret secret. It
+// replaces the user's recovery codes with new ones, which are returned. Only the hashes of the
+// recovery codes are stored.
+func (m *UserModel) EnableTOTP(id int, secret string) ([]string, error) {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    stmt := `UPDATE user
+                SET totp_secret = ?, totp_step = 0
+              WHERE id = ?`
+
+    _, err = tx.Exec(stmt, secret, id)
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = tx.Exec(`DELETE FROM recovery_code WHERE user_id = ?`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    codes := make([]string, recoveryCodeCount)
+
+    for i := range codes {
+        codes[i], err = newRecoveryCode()
+        if err != nil {
+            return nil, err
+        }
+
+        _, err = tx.Exec(`INSERT INTO recovery_code(user_id, hash) VALUES(?, ?)`, id, hashToken(normalizeRecoveryCode(codes[i])))
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return codes, tx.Commit()
+}
+
+// DisableTOTP turns off two-factor authentication for the user id and deletes their recovery
+// codes.
+func (m *UserModel) DisableTOTP(id int) error {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    _, err = tx.Exec(`UPDATE user SET totp_secret = NULL, totp_step = 0 WHERE id = ?`, id)
+    if err != nil {
+        return err
+    }
+
+    _, err = tx.Exec(`DELETE FROM recovery_code WHERE user_id = ?`, id)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+// CheckTOTP checks the one-time password code of the user id at time now. A code is only accepted
+// once, so that it can't be replayed by someone who saw it being entered. CheckTOTP returns
+// ErrInvalidCredentials if the code is wrong or has been used.
+func (m *UserModel) CheckTOTP(id int, code string, now time.Time) error {
+    tx, err := m.DB.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    // Lock the user so that concurrent requests can't both use the same code.
+    stmt := `SELECT totp_secret, totp_step
+               FROM user
+              WHERE id = ?
+                FOR UPDATE`
+
+    var (
+        secret   sql.NullString
+        lastStep int64
+    )
+
+    err = tx.QueryRow(stmt, id).Scan(&secret, &lastStep)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrNoRecord
+        } else {
+            return err
+        }
+    }
+
+    if !secret.Valid {
+        return ErrInvalidCredentials
+    }
+
+    // Accept the codes of the previous and next steps too, in case either clock is a little out.
+    step, ok := totp.Match(secret.String, code, now, 1)
+    if !ok || step <= lastStep {
+        return ErrInvalidCredentials
+    }
+
+    _, err = tx.Exec(`UPDATE user SET totp_step = ? WHERE id = ?`, step, id)
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+// UseRecoveryCode deletes the recovery code code of the user id, so that it can't be used again.
+// It returns ErrInvalidCredentials if the user has no such recovery code.
+func (m *UserModel) UseRecoveryCode(id int, code string) error {
+    stmt := `DELETE FROM recovery_code
+              WHERE user_id = ?
+                AND hash = ?`
+
+    result, err := m.DB.Exec(stmt, id, hashToken(normalizeRecoveryCode(code)))
+    if err != nil {
+        return err
+    }
+
+    n, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return ErrInvalidCredentials
+    }
+
+    return nil
+}
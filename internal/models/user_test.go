@@ -2,7 +2,10 @@ package models
 
 import (
 	"snippetbox/internal/assert"
+	"snippetbox/internal/totp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestUserModelExists(t *testing.T) {
@@ -52,3 +55,69 @@ func TestUserModelExists(t *testing.T) {
         })
     }
 }
+
+func TestUserModelVerify(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+    m := UserModel{db}
+
+    id, err := m.Insert("Bob", "bob@example.com", "pa$$word")
+    assert.NilError(t, err)
+
+    // New users can't log in until they have verified their email address.
+    authID, err := m.Authenticate("bob@example.com", "pa$$word")
+    assert.Equal(t, authID, id)
+    assert.Equal(t, err, ErrUnverifiedEmail)
+
+    assert.Equal(t, m.Verify(id, "robert@example.com"), ErrNoRecord)
+    assert.NilError(t, m.Verify(id, "bob@example.com"))
+    // Verifying twice is harmless.
+    assert.NilError(t, m.Verify(id, "bob@example.com"))
+
+    authID, err = m.Authenticate("bob@example.com", "pa$$word")
+    assert.Equal(t, authID, id)
+    assert.NilError(t, err)
+}
+
+func TestUserModelTOTP(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+    m := UserModel{db}
+
+    secret, err := totp.NewSecret()
+    assert.NilError(t, err)
+
+    codes, err := m.EnableTOTP(1, secret)
+    assert.NilError(t, err)
+    assert.Equal(t, len(codes), recoveryCodeCount)
+
+    user, err := m.Get(1)
+    assert.NilError(t, err)
+    assert.Equal(t, user.TOTPEnabled, true)
+
+    now := time.Now()
+    otp, err := totp.Code(secret, totp.Step(now))
+    assert.NilError(t, err)
+
+    assert.NilError(t, m.CheckTOTP(1, otp, now))
+    // A code can't be used twice.
+    assert.Equal(t, m.CheckTOTP(1, otp, now), ErrInvalidCredentials)
+
+    // Recovery codes are accepted however they are formatted, but only once.
+    assert.NilError(t, m.UseRecoveryCode(1, strings.ToUpper(codes[0])))
+    assert.Equal(t, m.UseRecoveryCode(1, codes[0]), ErrInvalidCredentials)
+    assert.NilError(t, m.UseRecoveryCode(1, strings.ReplaceAll(codes[1], "-", "")))
+
+    assert.NilError(t, m.DisableTOTP(1))
+    assert.Equal(t, m.UseRecoveryCode(1, codes[2]), ErrInvalidCredentials)
+
+    user, err = m.Get(1)
+    assert.NilError(t, err)
+    assert.Equal(t, user.TOTPEnabled, false)
+}
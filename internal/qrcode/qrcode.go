@@ -0,0 +1,524 @@
+// Package qrcode encodes data as QR codes (ISO/IEC 18004) and renders them as SVG. It supports
+// byte mode and versions 1 to 10, which hold up to 271 bytes, enough for URLs such as the
+// provisioning URIs of authenticator apps.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong is returned by Encode when the data doesn't fit in the largest supported version.
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// Level is the error correction level of a QR code. Higher levels can be read when more of the
+// code is damaged, but hold less data.
+type Level int
+
+const (
+    L Level = iota // Recovers 7% of the code.
+    M              // Recovers 15% of the code.
+    Q              // Recovers 25% of the code.
+    H              // Recovers 30% of the code.
+)
+
+// formatBits returns the two bits which identify level in the format information.
+func (level Level) formatBits() int {
+    return [...]int{1, 0, 3, 2}[level]
+}
+
+// blocks describes how the codewords of a version and level are split into error correction
+// blocks. There are n1 blocks of k1 data codewords followed by n2 blocks of k1+1 data codewords,
+// and every block has ec error correction codewords.
+type blocks struct {
+    ec, n1, k1, n2 int
+}
+
+// blockTable holds the block structure of versions 1 to 10 for levels L, M, Q and H.
+var blockTable = [...][4]blocks{
+    {{7, 1, 19, 0}, {10, 1, 16, 0}, {13, 1, 13, 0}, {17, 1, 9, 0}},
+    {{10, 1, 34, 0}, {16, 1, 28, 0}, {22, 1, 22, 0}, {28, 1, 16, 0}},
+    {{15, 1, 55, 0}, {26, 1, 44, 0}, {18, 2, 17, 0}, {22, 2, 13, 0}},
+    {{20, 1, 80, 0}, {18, 2, 32, 0}, {26, 2, 24, 0}, {16, 4, 9, 0}},
+    {{26, 1, 108, 0}, {24, 2, 43, 0}, {18, 2, 15, 2}, {22, 2, 11, 2}},
+    {{18, 2, 68, 0}, {16, 4, 27, 0}, {24, 4, 19, 0}, {28, 4, 15, 0}},
+    {{20, 2, 78, 0}, {18, 4, 31, 0}, {18, 2, 14, 4}, {26, 4, 13, 1}},
+    {{24, 2, 97, 0}, {22, 2, 38, 2}, {22, 4, 18, 2}, {26, 4, 14, 2}},
+    {{30, 2, 116, 0}, {22, 3, 36, 2}, {20, 4, 16, 4}, {24, 4, 12, 4}},
+    {{18, 2, 68, 2}, {26, 4, 43, 1}, {24, 6, 19, 2}, {28, 6, 15, 2}},
+}
+
+// alignmentTable holds the row and column coordinates of the alignment patterns of versions 1
+// to 10.
+var alignmentTable = [...][]int{
+    nil,
+    {6, 18},
+    {6, 22},
+    {6, 26},
+    {6, 30},
+    {6, 34},
+    {6, 22, 38},
+    {6, 24, 42},
+    {6, 26, 46},
+    {6, 28, 50},
+}
+
+// maxVersion is the largest version supported by Encode.
+const maxVersion = len(blockTable)
+
+// dataCodewords returns the number of data codewords of version and level.
+func dataCodewords(version int, level Level) int {
+    b := blockTable[version-1][level]
+
+    return b.n1*b.k1 + b.n2*(b.k1+1)
+}
+
+// Code is a QR code. Its modules are either dark or light.
+type Code struct {
+    Size     int  // The number of modules along each side, not counting the quiet zone.
+    Version  int
+    Level    Level
+    modules  []bool
+    reserved []bool  // Whether each module is part of a function pattern rather than data.
+}
+
+// Dark reports whether the module in column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+    return c.modules[y*c.Size+x]
+}
+
+func (c *Code) set(x, y int, dark bool) {
+    c.modules[y*c.Size+x] = dark
+}
+
+// setFunction sets a module which belongs to a function pattern, so that no data is placed there
+// and it isn't masked.
+func (c *Code) setFunction(x, y int, dark bool) {
+    c.set(x, y, dark)
+    c.reserved[y*c.Size+x] = true
+}
+
+// Encode returns the smallest QR code which holds data at error correction level level.
+func Encode(data []byte, level Level) (*Code, error) {
+    version := 0
+    for v := 1; v <= maxVersion; v++ {
+        if 4+countBits(v)+8*len(data) <= 8*dataCodewords(v, level) {
+            version = v
+            break
+        }
+    }
+    if version == 0 {
+        return nil, ErrTooLong
+    }
+
+    size := 4*version + 17
+    c := &Code{
+        Size:     size,
+        Version:  version,
+        Level:    level,
+        modules:  make([]bool, size*size),
+        reserved: make([]bool, size*size),
+    }
+
+    c.drawFunctionPatterns()
+    c.drawCodewords(interleave(encodeData(data, version, level), version, level))
+
+    // Use the mask which makes the code easiest to read.
+    best, bestPenalty := 0, -1
+    for mask := 0; mask < 8; mask++ {
+        c.applyMask(mask)
+        c.drawFormatBits(mask)
+
+        penalty := c.penalty()
+        if bestPenalty < 0 || penalty < bestPenalty {
+            best, bestPenalty = mask, penalty
+        }
+
+        // Masking twice undoes the mask.
+        c.applyMask(mask)
+    }
+
+    c.applyMask(best)
+    c.drawFormatBits(best)
+
+    return c, nil
+}
+
+// countBits returns the length of the character count indicator of byte mode in version.
+func countBits(version int) int {
+    if version < 10 {
+        return 8
+    }
+
+    return 16
+}
+
+// bitBuffer is a sequence of bits, which are appended most significant bit first.
+type bitBuffer struct {
+    bytes []byte
+    n     int
+}
+
+func (b *bitBuffer) append(value, bits int) {
+    for i := bits - 1; i >= 0; i-- {
+        if b.n%8 == 0 {
+            b.bytes = append(b.bytes, 0)
+        }
+        if value>>i&1 == 1 {
+            b.bytes[b.n/8] |= 0x80 >> (b.n % 8)
+        }
+        b.n++
+    }
+}
+
+// encodeData returns the data codewords which hold data in byte mode, padded to the capacity of
+// version and level.
+func encodeData(data []byte, version int, level Level) []byte {
+    capacity := dataCodewords(version, level)
+
+    var b bitBuffer
+
+    b.append(0b0100, 4)
+    b.append(len(data), countBits(version))
+    for _, d := range data {
+        b.append(int(d), 8)
+    }
+
+    // Add a terminator of up to four zero bits, then pad to a whole byte.
+    b.append(0, min(4, 8*capacity-b.n))
+    b.append(0, (8-b.n%8)%8)
+
+    for pad := 0; len(b.bytes) < capacity; pad++ {
+        if pad%2 == 0 {
+            b.bytes = append(b.bytes, 0xec)
+        } else {
+            b.bytes = append(b.bytes, 0x11)
+        }
+    }
+
+    return b.bytes
+}
+
+// interleave splits data into the blocks of version and level, adds error correction codewords
+// to each block and returns the codewords of the blocks interleaved.
+func interleave(data []byte, version int, level Level) []byte {
+    b := blockTable[version-1][level]
+
+    var dataBlocks, ecBlocks [][]byte
+
+    for i := 0; i < b.n1+b.n2; i++ {
+        k := b.k1
+        if i >= b.n1 {
+            k++
+        }
+
+        dataBlocks = append(dataBlocks, data[:k])
+        ecBlocks = append(ecBlocks, reedSolomon(data[:k], b.ec))
+        data = data[k:]
+    }
+
+    var result []byte
+
+    for i := 0; i <= b.k1; i++ {
+        for _, block := range dataBlocks {
+            if i < len(block) {
+                result = append(result, block[i])
+            }
+        }
+    }
+    for i := 0; i < b.ec; i++ {
+        for _, block := range ecBlocks {
+            result = append(result, block[i])
+        }
+    }
+
+    return result
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns, and the version
+// information, and reserves the modules of the format information.
+func (c *Code) drawFunctionPatterns() {
+    for i := 0; i < c.Size; i++ {
+        c.setFunction(6, i, i%2 == 0)
+        c.setFunction(i, 6, i%2 == 0)
+    }
+
+    c.drawFinderPattern(3, 3)
+    c.drawFinderPattern(c.Size-4, 3)
+    c.drawFinderPattern(3, c.Size-4)
+
+    positions := alignmentTable[c.Version-1]
+    last := len(positions) - 1
+    for i, x := range positions {
+        for j, y := range positions {
+            // Skip the alignment patterns which would overlap the finder patterns.
+            if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+                continue
+            }
+            c.drawAlignmentPattern(x, y)
+        }
+    }
+
+    c.drawFormatBits(0)
+    c.drawVersion()
+}
+
+// drawFinderPattern draws a finder pattern and its separator centred on column x and row y.
+func (c *Code) drawFinderPattern(x, y int) {
+    for dy := -4; dy <= 4; dy++ {
+        for dx := -4; dx <= 4; dx++ {
+            xx, yy := x+dx, y+dy
+            if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+                continue
+            }
+
+            d := max(abs(dx), abs(dy))
+            c.setFunction(xx, yy, d != 2 && d != 4)
+        }
+    }
+}
+
+// drawAlignmentPattern draws an alignment pattern centred on column x and row y.
+func (c *Code) drawAlignmentPattern(x, y int) {
+    for dy := -2; dy <= 2; dy++ {
+        for dx := -2; dx <= 2; dx++ {
+            c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+        }
+    }
+}
+
+// drawFormatBits draws both copies of the format information, which holds the error correction
+// level and mask, and the dark module beside them.
+func (c *Code) drawFormatBits(mask int) {
+    data := c.Level.formatBits()<<3 | mask
+    bits := (data<<10 | bchRemainder(data, 0x537, 10)) ^ 0x5412
+
+    bit := func(i int) bool {
+        return bits>>i&1 == 1
+    }
+
+    // The copy around the top left finder pattern.
+    for i := 0; i <= 5; i++ {
+        c.setFunction(8, i, bit(i))
+    }
+    c.setFunction(8, 7, bit(6))
+    c.setFunction(8, 8, bit(7))
+    c.setFunction(7, 8, bit(8))
+    for i := 9; i < 15; i++ {
+        c.setFunction(14-i, 8, bit(i))
+    }
+
+    // The copy split between the other two finder patterns.
+    for i := 0; i < 8; i++ {
+        c.setFunction(c.Size-1-i, 8, bit(i))
+    }
+    for i := 8; i < 15; i++ {
+        c.setFunction(8, c.Size-15+i, bit(i))
+    }
+    c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information, which versions 7 and above have.
+func (c *Code) drawVersion() {
+    if c.Version < 7 {
+        return
+    }
+
+    bits := c.Version<<12 | bchRemainder(c.Version, 0x1f25, 12)
+
+    for i := 0; i < 18; i++ {
+        dark := bits>>i&1 == 1
+        a, b := c.Size-11+i%3, i/3
+
+        c.setFunction(a, b, dark)
+        c.setFunction(b, a, dark)
+    }
+}
+
+// bchRemainder returns the remainder of data shifted left by bits, divided by the generator
+// polynomial poly of the BCH code protecting the format and version information.
+func bchRemainder(data, poly, bits int) int {
+    rem := data
+    for i := 0; i < bits; i++ {
+        rem = rem<<1 ^ (rem>>(bits-1))*poly
+    }
+
+    return rem & (1<<bits - 1)
+}
+
+// drawCodewords places codewords in the data modules, in two-module wide columns which zigzag up
+// and down from the bottom right corner.
+func (c *Code) drawCodewords(codewords []byte) {
+    i := 0
+
+    for right := c.Size - 1; right >= 1; right -= 2 {
+        // The vertical timing pattern is skipped.
+        if right == 6 {
+            right = 5
+        }
+
+        upward := (right+1)&2 == 0
+
+        for vert := 0; vert < c.Size; vert++ {
+            y := vert
+            if upward {
+                y = c.Size - 1 - vert
+            }
+
+            for x := right; x >= right-1; x-- {
+                if c.reserved[y*c.Size+x] {
+                    continue
+                }
+
+                // Any remainder bits are left light.
+                if i < 8*len(codewords) {
+                    c.set(x, y, codewords[i/8]>>(7-i%8)&1 == 1)
+                    i++
+                }
+            }
+        }
+    }
+}
+
+// applyMask inverts the data modules selected by mask.
+func (c *Code) applyMask(mask int) {
+    for y := 0; y < c.Size; y++ {
+        for x := 0; x < c.Size; x++ {
+            if c.reserved[y*c.Size+x] {
+                continue
+            }
+
+            var invert bool
+            switch mask {
+            case 0:
+                invert = (x+y)%2 == 0
+            case 1:
+                invert = y%2 == 0
+            case 2:
+                invert = x%3 == 0
+            case 3:
+                invert = (x+y)%3 == 0
+            case 4:
+                invert = (x/3+y/2)%2 == 0
+            case 5:
+                invert = x*y%2+x*y%3 == 0
+            case 6:
+                invert = (x*y%2+x*y%3)%2 == 0
+            case 7:
+                invert = ((x+y)%2+x*y%3)%2 == 0
+            }
+
+            if invert {
+                c.set(x, y, !c.Dark(x, y))
+            }
+        }
+    }
+}
+
+// finderLike is a run of modules which looks like part of a finder pattern, with four light
+// modules on one side.
+var finderLike = [2][11]bool{
+    {true, false, true, true, true, false, true, false, false, false, false},
+    {false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores how hard the code is to read, following the rules of the standard: long runs of
+// modules of the same colour, 2x2 blocks of the same colour, patterns which look like finder
+// patterns, and an imbalance of dark and light modules are penalised.
+func (c *Code) penalty() int {
+    penalty := 0
+
+    // Both rows and columns are scored, by swapping the coordinates for columns.
+    for _, transpose := range []bool{false, true} {
+        dark := func(x, y int) bool {
+            if transpose {
+                return c.Dark(y, x)
+            }
+            return c.Dark(x, y)
+        }
+
+        for y := 0; y < c.Size; y++ {
+            run := 1
+            for x := 1; x <= c.Size; x++ {
+                if x < c.Size && dark(x, y) == dark(x-1, y) {
+                    run++
+                    continue
+                }
+                if run >= 5 {
+                    penalty += run - 2
+                }
+                run = 1
+            }
+
+            for x := 0; x+11 <= c.Size; x++ {
+                for _, pattern := range finderLike {
+                    matches := true
+                    for i, d := range pattern {
+                        if dark(x+i, y) != d {
+                            matches = false
+                            break
+                        }
+                    }
+                    if matches {
+                        penalty += 40
+                    }
+                }
+            }
+        }
+    }
+
+    for y := 0; y+1 < c.Size; y++ {
+        for x := 0; x+1 < c.Size; x++ {
+            d := c.Dark(x, y)
+            if c.Dark(x+1, y) == d && c.Dark(x, y+1) == d && c.Dark(x+1, y+1) == d {
+                penalty += 3
+            }
+        }
+    }
+
+    dark := 0
+    for _, m := range c.modules {
+        if m {
+            dark++
+        }
+    }
+    percent := dark * 100 / len(c.modules)
+    penalty += abs(percent-50) / 5 * 10
+
+    return penalty
+}
+
+// quietZone is the width in modules of the light border which must surround a QR code.
+const quietZone = 4
+
+// SVG returns an SVG image of the code, including its quiet zone, in which each module is one
+// unit wide. The image scales to the size of its container, or to width pixels if it is not
+// styled.
+func (c *Code) SVG(width int) string {
+    n := c.Size + 2*quietZone
+
+    var b strings.Builder
+
+    fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`, n, n, width, width)
+    fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/>`, n, n)
+    b.WriteString(`<path fill="#000" d="`)
+    for y := 0; y < c.Size; y++ {
+        for x := 0; x < c.Size; x++ {
+            if c.Dark(x, y) {
+                fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+            }
+        }
+    }
+    b.WriteString(`"/></svg>`)
+
+    return b.String()
+}
+
+func abs(n int) int {
+    if n < 0 {
+        return -n
+    }
+
+    return n
+}
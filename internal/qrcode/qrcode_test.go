@@ -0,0 +1,249 @@
+package qrcode
+
+import (
+	"bytes"
+	"snippetbox/internal/assert"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+    // The data codewords of "HELLO WORLD" in a version 1-M code, from the worked example of the
+    // standard.
+    data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+    expect := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+    assert.Equal(t, bytes.Equal(reedSolomon(data, 10), expect), true)
+}
+
+func TestBlockTable(t *testing.T) {
+    for version := 1; version <= maxVersion; version++ {
+        // The number of modules left for codewords once the function patterns are drawn.
+        raw := (16*version+128)*version + 64
+        if version >= 2 {
+            n := version/7 + 2
+            raw -= (25*n-10)*n - 55
+            if version >= 7 {
+                raw -= 36
+            }
+        }
+
+        for level := L; level <= H; level++ {
+            b := blockTable[version-1][level]
+            total := dataCodewords(version, level) + (b.n1+b.n2)*b.ec
+
+            assert.Equal(t, total, raw/8)
+        }
+    }
+}
+
+func TestFormatBits(t *testing.T) {
+    tests := []struct {
+        level  Level
+        mask   int
+        expect int
+    }{
+        {level: L, mask: 0, expect: 0b111011111000100},
+        {level: L, mask: 4, expect: 0b110011000101111},
+        {level: M, mask: 0, expect: 0b101010000010010},
+        {level: M, mask: 1, expect: 0b101000100100101},
+        {level: Q, mask: 0, expect: 0b011010101011111},
+        {level: H, mask: 0, expect: 0b001011010001001},
+    }
+
+    for _, tc := range tests {
+        data := tc.level.formatBits()<<3 | tc.mask
+        bits := (data<<10 | bchRemainder(data, 0x537, 10)) ^ 0x5412
+
+        assert.Equal(t, bits, tc.expect)
+    }
+}
+
+func TestVersionBits(t *testing.T) {
+    assert.Equal(t, 7<<12|bchRemainder(7, 0x1f25, 12), 0b000111110010010100)
+    assert.Equal(t, 10<<12|bchRemainder(10, 0x1f25, 12), 0b001010010011010011)
+}
+
+// decode reads the data of c back, checking the error correction codewords on the way.
+func decode(t *testing.T, c *Code) []byte {
+    t.Helper()
+
+    // Read the mask from the first copy of the format information.
+    bits := 0
+    for i := 0; i <= 5; i++ {
+        if c.Dark(8, i) {
+            bits |= 1 << i
+        }
+    }
+    for i, p := range [][2]int{{8, 7}, {8, 8}, {7, 8}} {
+        if c.Dark(p[0], p[1]) {
+            bits |= 1 << (6 + i)
+        }
+    }
+    for i := 9; i < 15; i++ {
+        if c.Dark(14-i, 8) {
+            bits |= 1 << i
+        }
+    }
+
+    mask := -1
+    for m := 0; m < 8; m++ {
+        data := c.Level.formatBits()<<3 | m
+        if bits == (data<<10|bchRemainder(data, 0x537, 10))^0x5412 {
+            mask = m
+        }
+    }
+    if mask < 0 {
+        t.Fatalf("invalid format information %015b", bits)
+    }
+
+    unmasked := &Code{
+        Size:     c.Size,
+        Version:  c.Version,
+        Level:    c.Level,
+        modules:  append([]bool(nil), c.modules...),
+        reserved: c.reserved,
+    }
+    unmasked.applyMask(mask)
+
+    var codewords []byte
+    i := 0
+    for right := c.Size - 1; right >= 1; right -= 2 {
+        if right == 6 {
+            right = 5
+        }
+        for vert := 0; vert < c.Size; vert++ {
+            y := vert
+            if (right+1)&2 == 0 {
+                y = c.Size - 1 - vert
+            }
+            for x := right; x >= right-1; x-- {
+                if c.reserved[y*c.Size+x] {
+                    continue
+                }
+                if i%8 == 0 {
+                    codewords = append(codewords, 0)
+                }
+                if unmasked.Dark(x, y) {
+                    codewords[i/8] |= 0x80 >> (i % 8)
+                }
+                i++
+            }
+        }
+    }
+
+    // De-interleave the blocks.
+    b := blockTable[c.Version-1][c.Level]
+    n := b.n1 + b.n2
+    blocks := make([][]byte, n)
+    pos := 0
+    for j := 0; j <= b.k1; j++ {
+        for k := range blocks {
+            if j < b.k1 || k >= b.n1 {
+                blocks[k] = append(blocks[k], codewords[pos])
+                pos++
+            }
+        }
+    }
+
+    var data []byte
+    for k, block := range blocks {
+        var ec []byte
+        for j := 0; j < b.ec; j++ {
+            ec = append(ec, codewords[pos+j*n+k])
+        }
+        if !bytes.Equal(ec, reedSolomon(block, b.ec)) {
+            t.Fatalf("block %d has wrong error correction codewords", k)
+        }
+        data = append(data, block...)
+    }
+
+    // Parse the byte mode segment.
+    if data[0]>>4 != 0b0100 {
+        t.Fatalf("unexpected mode %04b", data[0]>>4)
+    }
+    bitsAt := func(offset, n int) int {
+        v := 0
+        for j := 0; j < n; j++ {
+            v = v<<1 | int(data[(offset+j)/8]>>(7-(offset+j)%8)&1)
+        }
+        return v
+    }
+    length := bitsAt(4, countBits(c.Version))
+
+    result := make([]byte, length)
+    for j := range result {
+        result[j] = byte(bitsAt(4+countBits(c.Version)+8*j, 8))
+    }
+
+    return result
+}
+
+func TestEncode(t *testing.T) {
+    tests := []struct {
+        name          string
+        data          string
+        level         Level
+        expectVersion int
+    }{
+        {
+            name: "Short",
+            data: "hello",
+            level: M,
+            expectVersion: 1,
+        },
+        {
+            name: "Multiple blocks",
+            data: strings.Repeat("snippet ", 10),
+            level: Q,
+            expectVersion: 7,
+        },
+        {
+            name: "Provisioning URI",
+            data: "otpauth://totp/Snippetbox:alice@example.com?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=Snippetbox&algorithm=SHA1&digits=6&period=30",
+            level: M,
+            expectVersion: 8,
+        },
+        {
+            name: "Largest version",
+            data: strings.Repeat("x", 271),
+            level: L,
+            expectVersion: 10,
+        },
+    }
+
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            c, err := Encode([]byte(tc.data), tc.level)
+            assert.NilError(t, err)
+
+            assert.Equal(t, c.Version, tc.expectVersion)
+            assert.Equal(t, c.Size, 4*tc.expectVersion+17)
+            assert.Equal(t, string(decode(t, c)), tc.data)
+
+            // The finder patterns are intact.
+            for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+                assert.Equal(t, c.Dark(corner[0], corner[1]), true)
+                assert.Equal(t, c.Dark(corner[0]+1, corner[1]+1), false)
+                assert.Equal(t, c.Dark(corner[0]+3, corner[1]+3), true)
+            }
+        })
+    }
+
+    t.Run("Too long", func(t *testing.T) {
+        _, err := Encode(bytes.Repeat([]byte("x"), 272), L)
+
+        assert.Equal(t, err, ErrTooLong)
+    })
+}
+
+func TestSVG(t *testing.T) {
+    c, err := Encode([]byte("hello"), M)
+    assert.NilError(t, err)
+
+    svg := c.SVG(200)
+
+    assert.StringContains(t, svg, `viewBox="0 0 29 29" width="200" height="200"`)
+    // The top left module of the top left finder pattern, inside the quiet zone.
+    assert.StringContains(t, svg, `d="M4 4h1v1h-1z`)
+}
@@ -0,0 +1,49 @@
+package qrcode
+
+// gfMultiply returns the product of x and y in the Galois field GF(2^8) used by QR codes, whose
+// elements are polynomials over GF(2) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+    var z int
+
+    for i := 7; i >= 0; i-- {
+        z = z<<1 ^ (z>>7)*0x11d
+        if y>>i&1 == 1 {
+            z ^= int(x)
+        }
+    }
+
+    return byte(z)
+}
+
+// reedSolomon returns the n error correction codewords of data, which are the remainder of
+// dividing data by the generator polynomial of degree n.
+func reedSolomon(data []byte, n int) []byte {
+    // The coefficients of the generator polynomial (x - 1)(x - 2)(x - 2^2)...(x - 2^(n-1)), from
+    // the highest power to the lowest, leaving out the leading coefficient, which is 1.
+    generator := make([]byte, n)
+    generator[n-1] = 1
+
+    var root byte = 1
+    for i := 0; i < n; i++ {
+        for j := range generator {
+            generator[j] = gfMultiply(generator[j], root)
+            if j+1 < n {
+                generator[j] ^= generator[j+1]
+            }
+        }
+        root = gfMultiply(root, 2)
+    }
+
+    remainder := make([]byte, n)
+    for _, d := range data {
+        factor := d ^ remainder[0]
+        copy(remainder, remainder[1:])
+        remainder[n-1] = 0
+
+        for i, g := range generator {
+            remainder[i] ^= gfMultiply(g, factor)
+        }
+    }
+
+    return remainder
+}
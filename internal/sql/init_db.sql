@@ -74,4 +74,230 @@ CREATE DATABASE test_snippetbox CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci
 
 CREATE USER test_web;
 GRANT CREATE, DROP, ALTER, INDEX, SELECT, INSERT, UPDATE, DELETE ON test_snippetbox.* TO test_web;
-ALTER USER test_web IDENTIFIED BY 'test';
\ No newline at end of file
+ALTER USER test_web IDENTIFIED BY 'test';
+
+
+-- Every snippet belongs to the user who created it. The column is nullable so that snippets 
+-- created before ownership was tracked are kept.
+ALTER TABLE snippet ADD COLUMN user_id INTEGER;
+ALTER TABLE snippet ADD CONSTRAINT fk_snippet_user FOREIGN KEY (user_id) REFERENCES user(id);
+
+CREATE INDEX idx_snippet_user_id ON snippet(user_id);
+
+
+
+-- Editing a snippet never overwrites its content. Every save is stored as a new revision and 
+-- snippet.revision points at the current one. Revision 1 is the original content in table snippet.
+ALTER TABLE snippet ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE snippet_revision (
+    snippet_id INTEGER      NOT NULL,
+    revision   INTEGER      NOT NULL,
+    title      VARCHAR(100) NOT NULL,
+    content    TEXT         NOT NULL,
+    created    DATETIME     NOT NULL,
+    PRIMARY KEY (snippet_id, revision),
+    CONSTRAINT fk_snippet_revision_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE
+);
+
+
+
+-- Deleting a snippet moves it to the trash of its owner by setting deleted_at. Trashed snippets 
+-- can be restored or purged for good.
+ALTER TABLE snippet ADD COLUMN deleted_at DATETIME;
+
+
+
+-- Expired snippets are removed periodically by a background worker.
+CREATE INDEX idx_snippet_expires ON snippet(expires);
+
+
+
+-- Only public snippets are listed. Unlisted snippets can be reached by anyone who knows their 
+-- random slug, and private snippets only by their owner.
+ALTER TABLE snippet ADD COLUMN visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public';
+ALTER TABLE snippet ADD COLUMN slug VARCHAR(16);
+ALTER TABLE snippet ADD CONSTRAINT uc_snippet_slug UNIQUE (slug);
+
+
+
+-- Every snippet now has a random slug, and its page is served at /s/{slug}. Give the existing 
+-- snippets a slug in the same format as the application (11 characters of base64url).
+UPDATE snippet 
+   SET slug = LEFT(REPLACE(REPLACE(TO_BASE64(RANDOM_BYTES(8)), '+', '-'), '/', '_'), 11) 
+ WHERE slug IS NULL;
+COMMIT;
+
+ALTER TABLE snippet MODIFY slug VARCHAR(16) NOT NULL;
+
+
+
+-- Burn after reading snippets are deleted as soon as they are revealed for the first time.
+ALTER TABLE snippet ADD COLUMN burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+
+-- A snippet can be protected by a passphrase, which is stored as a bcrypt hash.
+ALTER TABLE snippet ADD COLUMN hashed_passphrase CHAR(60);
+
+
+
+-- A snippet whose expires is NULL never expires.
+ALTER TABLE snippet MODIFY expires DATETIME NULL;
+
+
+
+-- The language of a snippet decides how its content is highlighted.
+ALTER TABLE snippet ADD COLUMN language VARCHAR(20) NOT NULL DEFAULT 'text';
+
+
+
+-- Markdown snippets are rendered as HTML instead of being shown as code.
+ALTER TABLE snippet ADD COLUMN format ENUM('plain', 'markdown') NOT NULL DEFAULT 'plain';
+
+
+
+-- A snippet can hold further named files, each with its own language, besides its own content.
+CREATE TABLE snippet_file (
+    snippet_id INTEGER      NOT NULL,
+    position   INTEGER      NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    language   VARCHAR(20)  NOT NULL DEFAULT 'text',
+    content    TEXT         NOT NULL,
+    PRIMARY KEY (snippet_id, position),
+    CONSTRAINT fk_snippet_file_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE
+);
+
+
+
+-- Full-text indexes for searching the current title and content of snippets.
+CREATE FULLTEXT INDEX ft_snippet_title_content ON snippet(title, content);
+CREATE FULLTEXT INDEX ft_snippet_revision_title_content ON snippet_revision(title, content);
+
+
+
+-- Snippets can be labelled with any number of tags, which are shared between snippets.
+CREATE TABLE tag (
+    id   INTEGER     NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(30) NOT NULL,
+    CONSTRAINT uc_tag_name UNIQUE (name)
+);
+
+CREATE TABLE snippet_tag (
+    snippet_id INTEGER NOT NULL,
+    tag_id     INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id),
+    CONSTRAINT fk_snippet_tag_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_snippet_tag_tag FOREIGN KEY (tag_id) REFERENCES tag(id)
+);
+
+CREATE INDEX idx_snippet_tag_tag_id ON snippet_tag(tag_id);
+
+
+
+-- A snippet can be a fork of another snippet. A fork outlives the snippet it was forked from.
+ALTER TABLE snippet ADD COLUMN forked_from INTEGER;
+ALTER TABLE snippet ADD CONSTRAINT fk_snippet_forked_from FOREIGN KEY (forked_from) REFERENCES snippet(id) ON DELETE SET NULL;
+
+
+
+-- Users can star snippets to keep a list of their favourites.
+CREATE TABLE star (
+    user_id    INTEGER  NOT NULL,
+    snippet_id INTEGER  NOT NULL,
+    created    DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snippet_id),
+    CONSTRAINT fk_star_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_star_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_star_snippet_id ON star(snippet_id);
+CREATE INDEX idx_star_created ON star(created);
+
+
+
+-- Comments on snippets, which can reply to other comments and refer to a line of the snippet.
+CREATE TABLE comment (
+    id         INTEGER  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER  NOT NULL,
+    user_id    INTEGER  NOT NULL,
+    parent_id  INTEGER,
+    line       INTEGER,
+    content    TEXT     NOT NULL,
+    created    DATETIME NOT NULL,
+    deleted_at DATETIME,
+    CONSTRAINT fk_comment_snippet FOREIGN KEY (snippet_id) REFERENCES snippet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_parent FOREIGN KEY (parent_id) REFERENCES comment(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_comment_snippet_id_created ON comment(snippet_id, created);
+
+
+
+-- New users have to verify their email address before they can log in. Existing users are
+-- trusted.
+ALTER TABLE user ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE user SET verified = TRUE;
+
+
+
+-- Tokens which let users who have forgotten their password set a new one. Only the SHA-256 hash
+-- of each token is stored.
+CREATE TABLE password_reset_token (
+    hash    CHAR(64) NOT NULL PRIMARY KEY,
+    user_id INTEGER  NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    CONSTRAINT fk_password_reset_token_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_reset_token_expires ON password_reset_token(expires);
+
+
+
+-- Optional two-factor authentication with time-based one-time passwords. totp_step is the time
+-- step of the last code which was accepted, so that codes can't be replayed. Only the SHA-256
+-- hashes of recovery codes are stored.
+ALTER TABLE user ADD COLUMN totp_secret VARCHAR(32);
+ALTER TABLE user ADD COLUMN totp_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_code (
+    user_id INTEGER  NOT NULL,
+    hash    CHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, hash),
+    CONSTRAINT fk_recovery_code_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+
+
+-- Consecutive failed logins by client IP addresses ("ip:" followed by the address) and accounts
+-- ("account:" followed by the email address), so that password guessing can be slowed down and
+-- stopped. The failures of a subject are forgotten after a day without any.
+CREATE TABLE login_failure (
+    subject       VARCHAR(320) NOT NULL PRIMARY KEY,
+    failures      INTEGER      NOT NULL,
+    last_failure  DATETIME     NOT NULL,
+    blocked_until DATETIME
+);
+
+CREATE INDEX idx_login_failure_last_failure ON login_failure(last_failure);
+
+
+
+-- The sessions of the scs session store indexed by user, so that users can see where they are
+-- logged in and log out other sessions. Sessions which were created before this table are not
+-- listed. Rows whose session has ended are removed by the reaper.
+CREATE TABLE user_session (
+    id            INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    token         CHAR(43)     NOT NULL,
+    user_id       INTEGER      NOT NULL,
+    user_agent    VARCHAR(255) NOT NULL,
+    ip            VARCHAR(45)  NOT NULL,
+    created       DATETIME     NOT NULL,
+    last_activity DATETIME     NOT NULL,
+    CONSTRAINT fk_user_session_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+);
+
+ALTER TABLE user_session ADD CONSTRAINT uc_user_session_token UNIQUE (token);
+CREATE INDEX idx_user_session_user_id ON user_session(user_id);
@@ -0,0 +1,105 @@
+// Package totp implements the time-based one-time passwords of RFC 6238, with the parameters
+// which authenticator apps assume by default: HMAC-SHA1, 6 digits and a period of 30 seconds.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+    Digits = 6
+    Period = 30 * time.Second
+)
+
+// encoding is the base32 encoding of secrets, without padding as authenticator apps expect.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random 160-bit secret, encoded in base32.
+func NewSecret() (string, error) {
+    b := make([]byte, 20)
+
+    _, err := rand.Read(b)
+    if err != nil {
+        return "", err
+    }
+
+    return encoding.EncodeToString(b), nil
+}
+
+// URI returns the provisioning URI which adds the account account of issuer with secret to an
+// authenticator app, usually by scanning it as a QR code.
+func URI(issuer, account, secret string) string {
+    v := url.Values{}
+    v.Set("secret", secret)
+    v.Set("issuer", issuer)
+    v.Set("algorithm", "SHA1")
+    v.Set("digits", fmt.Sprint(Digits))
+    v.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+    return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + v.Encode()
+}
+
+// Step returns the number of the time step which contains t.
+func Step(t time.Time) int64 {
+    return t.Unix() / int64(Period.Seconds())
+}
+
+// hotp returns the HMAC-based one-time password of RFC 4226 for key and counter.
+func hotp(key []byte, counter int64, digits int) string {
+    var msg [8]byte
+    binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+    h := hmac.New(sha1.New, key)
+    h.Write(msg[:])
+    sum := h.Sum(nil)
+
+    // Dynamic truncation picks 31 bits of the HMAC at an offset given by its last nibble.
+    offset := sum[len(sum)-1] & 0x0f
+    code := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+    mod := uint32(1)
+    for i := 0; i < digits; i++ {
+        mod *= 10
+    }
+
+    return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// Code returns the one-time password for secret in the time step step.
+func Code(secret string, step int64) (string, error) {
+    key, err := encoding.DecodeString(strings.ToUpper(secret))
+    if err != nil {
+        return "", err
+    }
+
+    return hotp(key, step, Digits), nil
+}
+
+// Match looks for code among the one-time passwords for secret in the time step which contains t
+// and the skew steps either side of it, allowing for clocks which are slightly out. It returns
+// the step in which code is valid, or false if it isn't valid in any of them.
+func Match(secret, code string, t time.Time, skew int) (int64, bool) {
+    now := Step(t)
+
+    for step := now - int64(skew); step <= now+int64(skew); step++ {
+        expect, err := Code(secret, step)
+        if err != nil {
+            return 0, false
+        }
+
+        if subtle.ConstantTimeCompare([]byte(code), []byte(expect)) == 1 {
+            return step, true
+        }
+    }
+
+    return 0, false
+}
@@ -0,0 +1,73 @@
+package totp
+
+import (
+	"snippetbox/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestHOTP(t *testing.T) {
+    // The SHA-1 test vectors of RFC 6238, appendix B.
+    key := []byte("12345678901234567890")
+
+    tests := []struct {
+        unix   int64
+        expect string
+    }{
+        {unix: 59, expect: "94287082"},
+        {unix: 1111111109, expect: "07081804"},
+        {unix: 1111111111, expect: "14050471"},
+        {unix: 1234567890, expect: "89005924"},
+        {unix: 2000000000, expect: "69279037"},
+        {unix: 20000000000, expect: "65353130"},
+    }
+
+    for _, tc := range tests {
+        assert.Equal(t, hotp(key, Step(time.Unix(tc.unix, 0)), 8), tc.expect)
+    }
+}
+
+func TestMatch(t *testing.T) {
+    // The base32 encoding of the key of the test vectors.
+    const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+    now := time.Unix(1111111109, 0)
+
+    step, ok := Match(secret, "081804", now, 1)
+    assert.Equal(t, ok, true)
+    assert.Equal(t, step, Step(now))
+
+    // The code of the next step is accepted 30 seconds early.
+    next, err := Code(secret, Step(now)+1)
+    assert.NilError(t, err)
+
+    step, ok = Match(secret, next, now, 1)
+    assert.Equal(t, ok, true)
+    assert.Equal(t, step, Step(now)+1)
+
+    // But not a minute early.
+    later, err := Code(secret, Step(now)+2)
+    assert.NilError(t, err)
+
+    _, ok = Match(secret, later, now, 1)
+    assert.Equal(t, ok, false)
+
+    _, ok = Match(secret, "000000", now, 1)
+    assert.Equal(t, ok, false)
+}
+
+func TestURI(t *testing.T) {
+    uri := URI("Snippetbox", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+    assert.Equal(t, uri, "otpauth://totp/Snippetbox:alice@example.com?algorithm=SHA1&digits=6&issuer=Snippetbox&period=30&secret=JBSWY3DPEHPK3PXP")
+}
+
+func TestNewSecret(t *testing.T) {
+    secret, err := NewSecret()
+    assert.NilError(t, err)
+
+    assert.Equal(t, len(secret), 32)
+
+    _, err = Code(secret, 0)
+    assert.NilError(t, err)
+}
@@ -14,6 +14,14 @@ import (
 // it.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// FilenameRX matches file names made of letters, digits, spaces, dots, dashes and underscores
+// which don't start with a dot or a space, so that they can't name a hidden file or a directory.
+var FilenameRX = regexp.MustCompile(`^[\w-][\w. -]*$`)
+
+// TagRX matches tags of up to 30 lowercase letters, digits, pluses, dots and dashes which start
+// with a letter or a digit, such as "sql", "k8s" or "c++".
+var TagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]{0,29}$`)
+
 // NotEmpty reports whether s is empty after being trimmed.
 func NotEmpty(s string) bool {
     return strings.TrimSpace(s) != ""
@@ -39,6 +47,23 @@ func Match(s string, rx *regexp.Regexp) bool {
     return rx.MatchString(s)
 }
 
+// AllMatch reports whether every string in values matches the compiled regular expression pattern
+// (rx).
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+    for _, v := range values {
+        if !rx.MatchString(v) {
+            return false
+        }
+    }
+
+    return true
+}
+
+// MaxItems reports whether items holds no more than n items.
+func MaxItems[T any](items []T, n int) bool {
+    return len(items) <= n
+}
+
 // Validator contains structures that hold validation errors.
 type Validator struct {
     NonFieldErrors []string  // Holds validation errors which are not related to a specific form field.